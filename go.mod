@@ -2,9 +2,9 @@ module github.com/nicheinc/nullable/v2
 
 go 1.24
 
-require github.com/nicheinc/expect v0.2.0
-
 require (
-	github.com/google/go-cmp v0.5.9 // indirect
-	golang.org/x/exp v0.0.0-20230425010034-47ecfdc1ba53 // indirect
+	github.com/google/go-cmp v0.5.9
+	github.com/nicheinc/expect v0.2.0
 )
+
+require golang.org/x/exp v0.0.0-20230425010034-47ecfdc1ba53 // indirect
@@ -0,0 +1,329 @@
+package nup
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type updateApplier interface {
+	// applyTo applies the update to the given addressable value, returning
+	// false if the value's type doesn't match the update's.
+	applyTo(field reflect.Value) bool
+	// changes reports whether applying the update to the given value, whose
+	// type must match the update's, would change it.
+	changes(field reflect.Value) bool
+}
+
+// ApplyPatch applies each Update and SliceUpdate field of patch, a patch struct
+// or a pointer to one, to the field with the same name in the struct pointed to
+// by model. An Update[T] may be applied to a field of type T (see Update.Apply)
+// or *T (see Update.ApplyPtr), and a SliceUpdate[T] to a field of type []T.
+// Fields of patch that are themselves patch structs are applied recursively to
+// the corresponding struct or struct pointer fields of model, allocating nil
+// struct pointers as needed.
+//
+// For example:
+//
+//	type User struct {
+//		Name  string
+//		Email *string
+//	}
+//
+//	type UserPatch struct {
+//		Name  nup.Update[string] `json:"name"`
+//		Email nup.Update[string] `json:"email"`
+//	}
+//
+//	err := nup.ApplyPatch(&user, patch)
+//
+// ApplyPatch returns an error, without modifying model, if any update field of
+// patch has no counterpart in model of a matching type, or if a field of patch
+// is a pointer to a patch struct, which isn't supported. Other fields of patch
+// are ignored.
+func ApplyPatch(model, patch interface{}) error {
+	modelValue := reflect.ValueOf(model)
+	if modelValue.Kind() != reflect.Pointer || modelValue.IsNil() || modelValue.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("nup: ApplyPatch requires a non-nil pointer to a struct model, not %T", model)
+	}
+	patchValue, ok := structValue(patch)
+	if !ok {
+		return fmt.Errorf("nup: ApplyPatch requires a patch struct, not %T", patch)
+	}
+	modelValue = modelValue.Elem()
+
+	// Check every field before applying any, so that a mismatched patch
+	// leaves the model untouched.
+	if err := checkApplicable(modelValue.Type(), patchValue); err != nil {
+		return err
+	}
+	walkUpdates(patchValue, "", "", func(field patchField) {
+		update := field.value.Interface().(updateMarshaller)
+		if !update.IsChange() {
+			return
+		}
+		modelField, _ := lookupModelField(modelValue, field.name, true)
+		update.(updateApplier).applyTo(modelField)
+	})
+	return nil
+}
+
+// CopyOnWrite returns a copy of model, a struct or a pointer to one, to which
+// patch can be applied with ApplyPatch without affecting model. Since
+// ApplyPatch modifies the structs that model's struct pointer fields point to
+// in place, each struct pointer on the path to a field that patch changes
+// points to a copy of the original struct in the result. If model is a non-nil
+// pointer, the result points to a copy of the struct it points to. Other
+// pointers, slices, and maps are shared with model, since ApplyPatch replaces
+// rather than modifies them.
+//
+// For example, to patch a model that may be shared, e.g. by a cache:
+//
+//	patched := nup.CopyOnWrite(user, patch)
+//	err := nup.ApplyPatch(&patched, patch)
+func CopyOnWrite[M any](model M, patch interface{}) M {
+	var (
+		cp = model
+		v  = reflect.ValueOf(&cp).Elem()
+	)
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() || v.Elem().Kind() != reflect.Struct {
+			return cp
+		}
+		copyElem(v)
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return cp
+	}
+	copied := map[string]bool{}
+	for _, field := range Fields(patch) {
+		if field.Operation == OpNoop {
+			continue
+		}
+		var (
+			parts = strings.Split(field.Name, ".")
+			value = v
+		)
+		for i, part := range parts[:len(parts)-1] {
+			if value = value.FieldByName(part); !value.IsValid() {
+				// ApplyPatch will reject the patch.
+				break
+			}
+			if value.Kind() == reflect.Pointer {
+				if value.IsNil() || value.Elem().Kind() != reflect.Struct {
+					// ApplyPatch allocates a new struct.
+					break
+				}
+				if path := strings.Join(parts[:i+1], "."); !copied[path] {
+					copyElem(value)
+					copied[path] = true
+				}
+				value = value.Elem()
+			}
+			if value.Kind() != reflect.Struct {
+				break
+			}
+		}
+	}
+	return cp
+}
+
+// copyElem points the settable, non-nil pointer v to a copy of the value it
+// points to.
+func copyElem(v reflect.Value) {
+	elem := reflect.New(v.Type().Elem())
+	elem.Elem().Set(v.Elem())
+	v.Set(elem)
+}
+
+// DiffPatch turns each update of the patch struct pointed to by patch that
+// wouldn't change the corresponding field of model, a struct or a pointer to
+// one, into a no-op, as Update.Diff, Update.DiffPtr, and SliceUpdate.Diff do
+// for individual updates. It returns whether any changes remain, i.e. whether
+// applying the resulting patch to model with ApplyPatch would change it.
+//
+// DiffPatch can be used to skip writes, or to omit extraneous changes from an
+// audit log, when applying a patch would have no effect. Like ApplyPatch, it
+// returns an error, without modifying patch, if any update field of patch has
+// no counterpart in model of a matching type. A field of model within a nil
+// struct pointer is treated as the zero value.
+func DiffPatch(patch, model interface{}) (bool, error) {
+	patchValue := reflect.ValueOf(patch)
+	if patchValue.Kind() != reflect.Pointer || patchValue.IsNil() || patchValue.Elem().Kind() != reflect.Struct {
+		return false, fmt.Errorf("nup: DiffPatch requires a non-nil pointer to a patch struct, not %T", patch)
+	}
+	modelValue, ok := structValue(model)
+	if !ok {
+		return false, fmt.Errorf("nup: DiffPatch requires a struct model, not %T", model)
+	}
+	patchValue = patchValue.Elem()
+	if err := checkApplicable(modelValue.Type(), patchValue); err != nil {
+		return false, err
+	}
+	changed := false
+	walkUpdates(patchValue, "", "", func(field patchField) {
+		update := field.value.Interface().(updateMarshaller)
+		if !update.IsChange() {
+			return
+		}
+		modelField, ok := lookupModelField(modelValue, field.name, false)
+		if !ok {
+			fieldType, _ := modelFieldType(modelValue.Type(), field.name)
+			modelField = reflect.Zero(fieldType)
+		}
+		if update.(updateApplier).changes(modelField) {
+			changed = true
+		} else {
+			field.value.Set(reflect.Zero(field.Type))
+		}
+	})
+	return changed, nil
+}
+
+// DiffModels sets the fields of the patch struct pointed to by patch to the
+// changes that transform before into after, two structs (or pointers to
+// structs) of the same type. Each field of patch whose counterpart differs
+// between before and after is set to the value of the field in after, even if
+// that's the zero value, e.g. "" or 0, or removed if it's a nil pointer or nil
+// slice; each other field of patch becomes a no-op. DiffModels returns whether
+// any field differs, i.e. whether the resulting patch is non-empty.
+//
+// For example, a client holding a snapshot of a model can compute the minimal
+// patch to send after modifying a copy of it:
+//
+//	edited := user
+//	edited.Name = "Bob"
+//	var patch UserPatch
+//	changed, err := nup.DiffModels(&patch, user, edited)
+//
+// Applying the patch to before with ApplyPatch yields after, except that fields
+// of after with no counterpart in patch are ignored. DiffModels returns an
+// error, without modifying patch, if any update field of patch has no
+// counterpart in the models of a matching type, or if a field of patch is a
+// pointer to a patch struct.
+func DiffModels(patch, before, after interface{}) (bool, error) {
+	patchValue := reflect.ValueOf(patch)
+	if patchValue.Kind() != reflect.Pointer || patchValue.IsNil() || patchValue.Elem().Kind() != reflect.Struct {
+		return false, fmt.Errorf("nup: DiffModels requires a non-nil pointer to a patch struct, not %T", patch)
+	}
+	beforeValue, ok := structValue(before)
+	if !ok {
+		return false, fmt.Errorf("nup: DiffModels requires a struct model, not %T", before)
+	}
+	afterValue, ok := structValue(after)
+	if !ok || afterValue.Type() != beforeValue.Type() {
+		return false, fmt.Errorf("nup: DiffModels requires models of the same type, not %T and %T", before, after)
+	}
+	if err := checkPatchType(patchValue.Type().Elem()); err != nil {
+		return false, err
+	}
+	modelType := beforeValue.Type()
+	fields := patchFields(patch)
+	for _, field := range fields {
+		fieldType, ok := modelFieldType(modelType, field.name)
+		if !ok {
+			return false, fmt.Errorf("nup: model %s has no field %s", modelType, field.name)
+		}
+		check := reflect.New(field.Type)
+		if !check.Interface().(updateAssigner).assignValue(reflect.Zero(fieldType)) {
+			return false, fmt.Errorf("nup: cannot assign %s from field %s of type %s", field.Type, field.name, fieldType)
+		}
+	}
+	// modelField returns the named field of the model v, or the zero value
+	// and false if it's within a nil struct pointer.
+	modelField := func(v reflect.Value, name string) (reflect.Value, bool) {
+		if field, ok := lookupModelField(v, name, false); ok {
+			return field, true
+		}
+		fieldType, _ := modelFieldType(modelType, name)
+		return reflect.Zero(fieldType), false
+	}
+	changed := false
+	for _, field := range fields {
+		var (
+			update         = reflect.New(field.Type)
+			assigner       = update.Interface().(updateAssigner)
+			afterField, ok = modelField(afterValue, field.name)
+		)
+		if ok {
+			assigner.assignValue(afterField)
+		} else {
+			// A field within a nil struct pointer is absent, so it's
+			// removed, even if it's not itself a pointer or slice.
+			assigner.assignFrom(afterField)
+		}
+		beforeField, _ := modelField(beforeValue, field.name)
+		if update.Elem().Interface().(updateApplier).changes(beforeField) {
+			field.value.Set(update.Elem())
+			changed = true
+		} else {
+			field.value.Set(reflect.Zero(field.Type))
+		}
+	}
+	return changed, nil
+}
+
+// checkApplicable returns an error if any update field of the patch struct
+// patchValue has no counterpart in the struct type modelType of a matching
+// type.
+func checkApplicable(modelType reflect.Type, patchValue reflect.Value) error {
+	if err := checkPatchType(patchValue.Type()); err != nil {
+		return err
+	}
+	var err error
+	walkUpdates(patchValue, "", "", func(field patchField) {
+		if err != nil {
+			return
+		}
+		fieldType, ok := modelFieldType(modelType, field.name)
+		if !ok {
+			err = fmt.Errorf("nup: model %s has no field %s", modelType, field.name)
+			return
+		}
+		if !field.value.Interface().(updateApplier).applyTo(reflect.New(fieldType).Elem()) {
+			err = fmt.Errorf("nup: cannot apply %s to field %s of type %s", field.Type, field.name, fieldType)
+		}
+	})
+	return err
+}
+
+// modelFieldType returns the type of the field of the struct type t with the
+// given name, which may be qualified with the names of enclosing struct or
+// struct pointer fields, e.g. "Address.City".
+func modelFieldType(t reflect.Type, name string) (reflect.Type, bool) {
+	for _, part := range strings.Split(name, ".") {
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return nil, false
+		}
+		field, ok := t.FieldByName(part)
+		if !ok || !field.IsExported() {
+			return nil, false
+		}
+		t = field.Type
+	}
+	return t, true
+}
+
+// lookupModelField returns the field of the struct v with the given name, which
+// may be qualified with the names of enclosing struct or struct pointer fields,
+// e.g. "Address.City". If alloc is true, it allocates nil struct pointers along
+// the way; otherwise, it returns false if it encounters one.
+func lookupModelField(v reflect.Value, name string, alloc bool) (reflect.Value, bool) {
+	for _, part := range strings.Split(name, ".") {
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.FieldByName(part)
+	}
+	return v, true
+}
@@ -0,0 +1,566 @@
+package nup
+
+import (
+	"testing"
+
+	"github.com/nicheinc/expect"
+)
+
+type testAddress struct {
+	City string
+	Zip  *string
+}
+
+type testModel struct {
+	ID      int
+	Name    string
+	Age     *int
+	Tags    []int
+	Address *testAddress
+	Note    string
+}
+
+func TestApplyPatch(t *testing.T) {
+	var (
+		age = 30
+		zip = "12345"
+	)
+	testCases := []struct {
+		name     string
+		model    testModel
+		patch    testPatch
+		expected testModel
+	}{
+		{
+			name: "Noop",
+			model: testModel{
+				Name: "Alice",
+				Age:  &age,
+			},
+			patch: testPatch{},
+			expected: testModel{
+				Name: "Alice",
+				Age:  &age,
+			},
+		},
+		{
+			name: "SetAndRemove",
+			model: testModel{
+				ID:   1,
+				Name: "Alice",
+				Age:  &age,
+				Tags: []int{1},
+				Note: "unchanged",
+			},
+			patch: testPatch{
+				Name: Set("Bob"),
+				Age:  Remove[int](),
+				Tags: SliceRemoveOrSet([]int{2, 3}),
+				Note: "ignored",
+			},
+			expected: testModel{
+				ID:   1,
+				Name: "Bob",
+				Tags: []int{2, 3},
+				Note: "unchanged",
+			},
+		},
+		{
+			name:  "NestedAllocates",
+			model: testModel{},
+			patch: testPatch{
+				Address: testAddressPatch{
+					City: Set("Paris"),
+					Zip:  Set(zip),
+				},
+			},
+			expected: testModel{
+				Address: &testAddress{
+					City: "Paris",
+					Zip:  &zip,
+				},
+			},
+		},
+		{
+			name:  "NestedNoopDoesNotAllocate",
+			model: testModel{},
+			patch: testPatch{
+				Name: Set("Alice"),
+			},
+			expected: testModel{
+				Name: "Alice",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			model := testCase.model
+			err := ApplyPatch(&model, testCase.patch)
+			expect.ErrorNil(t, err)
+			expect.Equal(t, model, testCase.expected)
+		})
+	}
+}
+
+func TestApplyPatch_Errors(t *testing.T) {
+	type model struct {
+		Name string
+		Age  string
+	}
+	testCases := []struct {
+		name  string
+		model interface{}
+		patch interface{}
+	}{
+		{
+			name:  "ModelNotPointer",
+			model: model{},
+			patch: struct{ Name Update[string] }{},
+		},
+		{
+			name:  "PatchNotStruct",
+			model: &model{},
+			patch: 5,
+		},
+		{
+			name:  "MissingField",
+			model: &model{},
+			patch: struct{ Email Update[string] }{},
+		},
+		{
+			name:  "MismatchedType",
+			model: &model{},
+			patch: struct {
+				Name Update[string]
+				Age  Update[int]
+			}{
+				Name: Set("Alice"),
+			},
+		},
+		{
+			name:  "PointerToPatch",
+			model: &testModel{},
+			patch: testPointerPatch{Address: &testAddressPatch{City: Set("Paris")}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := ApplyPatch(testCase.model, testCase.patch)
+			expect.ErrorNonNil(t, err)
+			if m, ok := testCase.model.(*model); ok {
+				// The model must be left untouched.
+				expect.Equal(t, *m, model{})
+			}
+		})
+	}
+}
+
+func TestCopyOnWrite(t *testing.T) {
+	var (
+		zip   = "75001"
+		model = testModel{
+			Name:    "Alice",
+			Tags:    []int{1},
+			Address: &testAddress{City: "Paris", Zip: &zip},
+		}
+		original = model
+	)
+	testCases := []struct {
+		name           string
+		patch          testPatch
+		expected       testModel
+		expectedCopied bool
+	}{
+		{
+			name:           "Noop",
+			patch:          testPatch{},
+			expected:       model,
+			expectedCopied: false,
+		},
+		{
+			name:           "TopLevel",
+			patch:          testPatch{Name: Set("Bob"), Tags: SliceRemove[int]()},
+			expected:       testModel{Name: "Bob", Address: model.Address},
+			expectedCopied: false,
+		},
+		{
+			name:  "Nested",
+			patch: testPatch{Address: testAddressPatch{City: Set("Lyon"), Zip: Remove[string]()}},
+			expected: testModel{
+				Name:    "Alice",
+				Tags:    []int{1},
+				Address: &testAddress{City: "Lyon"},
+			},
+			expectedCopied: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			patched := CopyOnWrite(model, testCase.patch)
+			err := ApplyPatch(&patched, testCase.patch)
+			expect.ErrorNil(t, err)
+			expect.Equal(t, patched, testCase.expected)
+			expect.Equal(t, patched.Address != model.Address, testCase.expectedCopied)
+			// The original model, including the structs it points to, is
+			// left unchanged.
+			expect.Equal(t, model, original)
+			expect.Equal(t, *model.Address, testAddress{City: "Paris", Zip: &zip})
+
+			pointer := CopyOnWrite(&model, testCase.patch)
+			err = ApplyPatch(pointer, testCase.patch)
+			expect.ErrorNil(t, err)
+			expect.Equal(t, *pointer, testCase.expected)
+			expect.Equal(t, *model.Address, testAddress{City: "Paris", Zip: &zip})
+		})
+	}
+}
+
+func TestCopyOnWrite_NilPointers(t *testing.T) {
+	var nilModel *testModel
+	expect.Equal(t, CopyOnWrite(nilModel, testPatch{Name: Set("Bob")}), nilModel)
+
+	patch := testPatch{Address: testAddressPatch{City: Set("Paris")}}
+	patched := CopyOnWrite(testModel{}, patch)
+	err := ApplyPatch(&patched, patch)
+	expect.ErrorNil(t, err)
+	expect.Equal(t, patched, testModel{Address: &testAddress{City: "Paris"}})
+}
+
+func TestDiffPatch(t *testing.T) {
+	var (
+		age = 30
+		zip = "12345"
+	)
+	model := testModel{
+		ID:   1,
+		Name: "Alice",
+		Age:  &age,
+		Tags: []int{1, 2},
+		Address: &testAddress{
+			City: "Paris",
+		},
+	}
+	testCases := []struct {
+		name            string
+		model           interface{}
+		patch           testPatch
+		expectedPatch   testPatch
+		expectedChanged bool
+	}{
+		{
+			name:            "Noop",
+			model:           model,
+			patch:           testPatch{},
+			expectedPatch:   testPatch{},
+			expectedChanged: false,
+		},
+		{
+			name:  "NoEffect",
+			model: model,
+			patch: testPatch{
+				ID:      Set(1),
+				Name:    Set("Alice"),
+				Age:     Set(30),
+				Tags:    SliceRemoveOrSet([]int{1, 2}),
+				Address: testAddressPatch{City: Set("Paris"), Zip: Remove[string]()},
+				Note:    "unchanged",
+			},
+			expectedPatch: testPatch{
+				Note: "unchanged",
+			},
+			expectedChanged: false,
+		},
+		{
+			name:  "SomeChanges",
+			model: &model,
+			patch: testPatch{
+				Name:    Set("Alice"),
+				Age:     Remove[int](),
+				Tags:    SliceRemoveOrSet([]int{2, 1}),
+				Address: testAddressPatch{City: Set("Paris"), Zip: Set(zip)},
+			},
+			expectedPatch: testPatch{
+				Age:     Remove[int](),
+				Tags:    SliceRemoveOrSet([]int{2, 1}),
+				Address: testAddressPatch{Zip: Set(zip)},
+			},
+			expectedChanged: true,
+		},
+		{
+			name:  "NilNestedStruct",
+			model: testModel{},
+			patch: testPatch{
+				Address: testAddressPatch{City: Set(""), Zip: Set(zip)},
+			},
+			expectedPatch: testPatch{
+				Address: testAddressPatch{Zip: Set(zip)},
+			},
+			expectedChanged: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			patch := testCase.patch
+			changed, err := DiffPatch(&patch, testCase.model)
+			expect.ErrorNil(t, err)
+			expect.Equal(t, changed, testCase.expectedChanged)
+			expect.Equal(t, patch, testCase.expectedPatch)
+		})
+	}
+}
+
+func TestDiffPatch_Errors(t *testing.T) {
+	type model struct {
+		Name string
+		Age  string
+	}
+	type patch struct {
+		Name Update[string]
+		Age  Update[int]
+	}
+	testCases := []struct {
+		name  string
+		patch interface{}
+		model interface{}
+	}{
+		{
+			name:  "PatchNotPointer",
+			patch: patch{},
+			model: model{},
+		},
+		{
+			name:  "ModelNotStruct",
+			patch: &patch{},
+			model: 5,
+		},
+		{
+			name:  "MismatchedType",
+			patch: &patch{Name: Set("Alice")},
+			model: model{},
+		},
+		{
+			name:  "PointerToPatch",
+			patch: &testPointerPatch{Address: &testAddressPatch{City: Set("Paris")}},
+			model: testModel{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := DiffPatch(testCase.patch, testCase.model)
+			expect.ErrorNonNil(t, err)
+		})
+	}
+}
+
+func TestDiffModels(t *testing.T) {
+	var (
+		age      = 30
+		otherAge = 31
+		zip      = "12345"
+	)
+	before := testModel{
+		ID:   1,
+		Name: "Alice",
+		Age:  &age,
+		Tags: []int{1, 2},
+		Address: &testAddress{
+			City: "Paris",
+		},
+		Note: "before",
+	}
+	testCases := []struct {
+		name            string
+		patch           testPatch
+		before          interface{}
+		after           interface{}
+		expectedPatch   testPatch
+		expectedChanged bool
+	}{
+		{
+			name:            "Equal",
+			before:          before,
+			after:           before,
+			expectedPatch:   testPatch{},
+			expectedChanged: false,
+		},
+		{
+			name:  "ExistingChangesCleared",
+			patch: testPatch{Name: Set("Bob"), Note: "kept"},
+			before: testModel{
+				Name: "Alice",
+			},
+			after: testModel{
+				Name: "Alice",
+				Note: "ignored",
+			},
+			expectedPatch:   testPatch{Note: "kept"},
+			expectedChanged: false,
+		},
+		{
+			name:   "SetAndRemove",
+			before: before,
+			after: &testModel{
+				ID:   2,
+				Name: "",
+				Age:  &otherAge,
+				Tags: []int{2, 1},
+				Address: &testAddress{
+					City: "Paris",
+					Zip:  &zip,
+				},
+			},
+			expectedPatch: testPatch{
+				ID:      Set(2),
+				Name:    Set(""),
+				Age:     Set(31),
+				Tags:    SliceRemoveOrSet([]int{2, 1}),
+				Address: testAddressPatch{Zip: Set(zip)},
+			},
+			expectedChanged: true,
+		},
+		{
+			name:   "NilPointers",
+			before: before,
+			after: testModel{
+				ID:   1,
+				Name: "Alice",
+			},
+			expectedPatch: testPatch{
+				Age:     Remove[int](),
+				Tags:    SliceRemove[int](),
+				Address: testAddressPatch{City: Remove[string]()},
+			},
+			expectedChanged: true,
+		},
+		{
+			name:   "ZeroValues",
+			before: before,
+			after: testModel{
+				ID:      1,
+				Name:    "",
+				Age:     &age,
+				Tags:    []int{},
+				Address: &testAddress{},
+			},
+			expectedPatch: testPatch{
+				Name:    Set(""),
+				Tags:    SliceRemoveOrSet([]int{}),
+				Address: testAddressPatch{City: Set("")},
+			},
+			expectedChanged: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			patch := testCase.patch
+			changed, err := DiffModels(&patch, testCase.before, testCase.after)
+			expect.ErrorNil(t, err)
+			expect.Equal(t, changed, testCase.expectedChanged)
+			expect.Equal(t, patch, testCase.expectedPatch)
+		})
+	}
+}
+
+// TestDiffModels_Apply ensures that applying the diff of two models to the
+// first yields the second.
+func TestDiffModels_Apply(t *testing.T) {
+	var (
+		age    = 30
+		zip    = "12345"
+		before = testModel{Name: "Alice", Age: &age, Tags: []int{1}}
+	)
+	testCases := []struct {
+		name  string
+		after testModel
+	}{
+		{
+			name:  "Changes",
+			after: testModel{Name: "Bob", Tags: []int{1, 2}, Address: &testAddress{City: "Paris", Zip: &zip}},
+		},
+		{
+			name:  "ZeroValues",
+			after: testModel{Name: "", Age: &age, Tags: []int{}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var (
+				patch  testPatch
+				result = before
+			)
+			_, err := DiffModels(&patch, before, testCase.after)
+			expect.ErrorNil(t, err)
+			err = ApplyPatch(&result, patch)
+			expect.ErrorNil(t, err)
+			expect.Equal(t, result, testCase.after)
+		})
+	}
+}
+
+func TestDiffModels_Errors(t *testing.T) {
+	type model struct {
+		Name string
+		Age  string
+	}
+	type patch struct {
+		Name Update[string]
+		Age  Update[int]
+	}
+	testCases := []struct {
+		name   string
+		patch  interface{}
+		before interface{}
+		after  interface{}
+	}{
+		{
+			name:   "PatchNotPointer",
+			patch:  patch{},
+			before: model{},
+			after:  model{},
+		},
+		{
+			name:   "BeforeNotStruct",
+			patch:  &patch{},
+			before: 5,
+			after:  model{},
+		},
+		{
+			name:   "MismatchedModels",
+			patch:  &patch{},
+			before: model{},
+			after:  testModel{},
+		},
+		{
+			name:   "MissingField",
+			patch:  &struct{ Email Update[string] }{},
+			before: model{},
+			after:  model{},
+		},
+		{
+			name:   "MismatchedType",
+			patch:  &patch{},
+			before: model{},
+			after:  model{Age: "30"},
+		},
+		{
+			name:   "PointerToPatch",
+			patch:  &testPointerPatch{},
+			before: testModel{},
+			after:  testModel{Address: &testAddress{City: "Paris"}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := DiffModels(testCase.patch, testCase.before, testCase.after)
+			expect.ErrorNonNil(t, err)
+		})
+	}
+}
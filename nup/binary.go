@@ -0,0 +1,56 @@
+package nup
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"errors"
+	"fmt"
+)
+
+// The binary form of an update is a single byte holding its Operation,
+// followed, for a set operation only, by the encoded value. This format is
+// stable: data written by one version of nup can be read by any later version.
+
+// appendBinaryValue appends the binary form of a set operation's value to
+// data. If the value implements encoding.BinaryMarshaler, its own binary form
+// is used; otherwise, it's encoded with encoding/gob.
+func appendBinaryValue(data []byte, value interface{}) ([]byte, error) {
+	if marshaler, ok := value.(encoding.BinaryMarshaler); ok {
+		valueData, err := marshaler.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		return append(data, valueData...), nil
+	}
+	buf := bytes.NewBuffer(data)
+	if err := gob.NewEncoder(buf).Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parseBinaryValue sets the value pointed to by ptr from its binary form, as
+// written by appendBinaryValue.
+func parseBinaryValue(data []byte, ptr interface{}) error {
+	if unmarshaler, ok := ptr.(encoding.BinaryUnmarshaler); ok {
+		return unmarshaler.UnmarshalBinary(data)
+	}
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(ptr)
+}
+
+// parseBinaryOperation returns the operation at the start of the binary form
+// of an update, along with the encoded value that follows it, if any.
+func parseBinaryOperation(data []byte) (Operation, []byte, error) {
+	if len(data) == 0 {
+		return 0, nil, errors.New("nup: cannot unmarshal empty binary data")
+	}
+	op, rest := Operation(data[0]), data[1:]
+	switch {
+	case !op.Valid():
+		return 0, nil, fmt.Errorf("nup: invalid binary operation %s", op)
+	case op != OpSet && len(rest) > 0:
+		return 0, nil, fmt.Errorf("nup: unexpected binary data after %s", op)
+	}
+	return op, rest, nil
+}
@@ -0,0 +1,199 @@
+package nup
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"testing"
+	"time"
+
+	"github.com/nicheinc/expect"
+)
+
+// Ensure implementation of the binary and gob interfaces.
+var (
+	_ encoding.BinaryMarshaler   = Update[int]{}
+	_ encoding.BinaryUnmarshaler = &Update[int]{}
+	_ gob.GobEncoder             = Update[int]{}
+	_ gob.GobDecoder             = &Update[int]{}
+	_ encoding.BinaryMarshaler   = SliceUpdate[int]{}
+	_ encoding.BinaryUnmarshaler = &SliceUpdate[int]{}
+	_ gob.GobEncoder             = SliceUpdate[int]{}
+	_ gob.GobDecoder             = &SliceUpdate[int]{}
+)
+
+var testTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// binaryUpdate is implemented by pointers to Update and SliceUpdate.
+type binaryUpdate interface {
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
+
+// TestBinary_Golden ensures that the binary form remains compatible with data
+// written by earlier versions.
+func TestBinary_Golden(t *testing.T) {
+	testCases := []struct {
+		name     string
+		golden   []byte
+		expected binaryUpdate
+		actual   binaryUpdate
+		// deterministic is false if the encoding depends on state other
+		// than the value, e.g. the encoding/gob type IDs allocated so far
+		// in the process, so only decoding can be checked.
+		deterministic bool
+	}{
+		{
+			name:          "Noop",
+			golden:        []byte("\x00"),
+			expected:      &Update[int]{},
+			actual:        &Update[int]{},
+			deterministic: true,
+		},
+		{
+			name:          "Remove",
+			golden:        []byte("\x01"),
+			expected:      ptr(Remove[int]()),
+			actual:        &Update[int]{},
+			deterministic: true,
+		},
+		{
+			name:          "SetInt",
+			golden:        []byte("\x02\x03\x04\x00T"),
+			expected:      ptr(Set(42)),
+			actual:        &Update[int]{},
+			deterministic: true,
+		},
+		{
+			name:          "SetString",
+			golden:        []byte("\x02\x05\f\x00\x02hi"),
+			expected:      ptr(Set("hi")),
+			actual:        &Update[string]{},
+			deterministic: true,
+		},
+		{
+			name:          "SetBinaryMarshaler",
+			golden:        []byte("\x02\x01\x00\x00\x00\x0e\xdd%t%\x00\x00\x00\x00\xff\xff"),
+			expected:      ptr(Set(testTime)),
+			actual:        &Update[time.Time]{},
+			deterministic: true,
+		},
+		{
+			name:          "SliceNoop",
+			golden:        []byte("\x00"),
+			expected:      &SliceUpdate[int]{},
+			actual:        &SliceUpdate[int]{},
+			deterministic: true,
+		},
+		{
+			name:          "SliceRemove",
+			golden:        []byte("\x01"),
+			expected:      ptr(SliceRemove[int]()),
+			actual:        &SliceUpdate[int]{},
+			deterministic: true,
+		},
+		{
+			name:     "SliceSet",
+			golden:   []byte("\x02\v\x7f\x02\x01\x02\xff\x80\x00\x01\x04\x00\x00\x06\xff\x80\x00\x02\x02\x04"),
+			expected: ptr(SliceRemoveOrSet([]int{1, 2})),
+			actual:   &SliceUpdate[int]{},
+		},
+		{
+			name:     "SliceSetEmpty",
+			golden:   []byte("\x02\f\xff\x81\x02\x01\x02\xff\x82\x00\x01\f\x00\x00\x04\xff\x82\x00\x00"),
+			expected: ptr(SliceRemoveOrSet([]string{})),
+			actual:   &SliceUpdate[string]{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := testCase.actual.UnmarshalBinary(testCase.golden)
+			expect.ErrorNil(t, err)
+			expect.Equal(t, testCase.actual, testCase.expected)
+
+			data, err := testCase.expected.MarshalBinary()
+			expect.ErrorNil(t, err)
+			if testCase.deterministic {
+				expect.Equal(t, data, testCase.golden)
+			}
+		})
+	}
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+func TestBinary_Gob(t *testing.T) {
+	type job struct {
+		Name    Update[string]
+		Age     Update[int]
+		Tags    SliceUpdate[string]
+		Batch   []Update[int]
+		Started Update[time.Time]
+	}
+	expected := job{
+		Name:    Set(""),
+		Age:     Remove[int](),
+		Tags:    SliceRemoveOrSet([]string{}),
+		Batch:   []Update[int]{Noop[int](), Remove[int](), Set(0)},
+		Started: Set(testTime),
+	}
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(expected)
+	expect.ErrorNil(t, err)
+
+	var actual job
+	err = gob.NewDecoder(&buf).Decode(&actual)
+	expect.ErrorNil(t, err)
+	expect.Equal(t, actual, expected)
+	// An empty set slice must remain distinct from a removal.
+	expect.Equal(t, actual.Tags.IsSet(), true)
+}
+
+func TestBinary_Errors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		data   []byte
+		update encoding.BinaryUnmarshaler
+	}{
+		{
+			name:   "Empty",
+			data:   nil,
+			update: &Update[int]{},
+		},
+		{
+			name:   "InvalidOperation",
+			data:   []byte{3},
+			update: &Update[int]{},
+		},
+		{
+			name:   "TrailingData",
+			data:   []byte{byte(OpRemove), 0},
+			update: &SliceUpdate[int]{},
+		},
+		{
+			name:   "MissingValue",
+			data:   []byte{byte(OpSet)},
+			update: &Update[int]{},
+		},
+		{
+			name:   "MismatchedType",
+			data:   []byte("\x02\x05\f\x00\x02hi"),
+			update: &Update[int]{},
+		},
+		{
+			name:   "InvalidBinaryMarshalerValue",
+			data:   []byte{byte(OpSet), 0xff},
+			update: &Update[time.Time]{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := testCase.update.UnmarshalBinary(testCase.data)
+			expect.ErrorNonNil(t, err)
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package nup
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// CanonicalJSON returns the canonical JSON encoding of v, as defined by the
+// JSON Canonicalization Scheme (JCS, RFC 8785), so that equal values always
+// have identical encodings. v is first marshalled with json.Marshal; then the
+// object members are sorted by key, insignificant whitespace is removed, and
+// numbers and strings are formatted as JCS prescribes. In particular, numbers
+// are formatted as IEEE 754 double-precision values, so integers beyond ±2^53
+// may lose precision.
+//
+// If v is a patch struct or a pointer to one, its no-ops are omitted, whether
+// or not its fields are marked with the "omitzero" JSON struct tag option, as
+// are any nested patch structs that make no changes. Thus, patches that make
+// the same changes have the same canonical encoding.
+func CanonicalJSON(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("nup: %w", err)
+	}
+	for _, field := range patchFields(v) {
+		if !field.value.Interface().(updateMarshaller).IsChange() {
+			deleteMember(value, pointerKeys(field.path))
+		}
+	}
+	var buf bytes.Buffer
+	if err := appendCanonical(&buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Hash returns a SHA-256 digest of the canonical JSON encoding of v, as
+// returned by CanonicalJSON. It can be used to recognize retried requests, or
+// otherwise to compare patches, regardless of differences in key order or
+// formatting.
+func Hash(v interface{}) ([sha256.Size]byte, error) {
+	data, err := CanonicalJSON(v)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(data), nil
+}
+
+// deleteMember deletes the member of the decoded JSON object value at the
+// given path of keys, if it's present, along with any enclosing objects that
+// are left empty, other than value itself.
+func deleteMember(value interface{}, keys []string) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if len(keys) == 1 {
+		delete(object, keys[0])
+		return
+	}
+	nested, ok := object[keys[0]]
+	if !ok {
+		return
+	}
+	deleteMember(nested, keys[1:])
+	if members, ok := nested.(map[string]interface{}); ok && len(members) == 0 {
+		delete(object, keys[0])
+	}
+}
+
+// appendCanonical writes the canonical encoding of the decoded JSON value to
+// buf.
+func appendCanonical(buf *bytes.Buffer, value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(value))
+	case json.Number:
+		number, err := canonicalNumber(value)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(number)
+	case string:
+		appendCanonicalString(buf, value)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, element := range value {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := appendCanonical(buf, element); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		// JCS sorts keys by their UTF-16 code units, which differs from
+		// sorting by bytes for characters outside the Basic Multilingual
+		// Plane.
+		sort.Slice(keys, func(i, j int) bool {
+			return lessUTF16(keys[i], keys[j])
+		})
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			appendCanonicalString(buf, key)
+			buf.WriteByte(':')
+			if err := appendCanonical(buf, value[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	}
+	return nil
+}
+
+// canonicalNumber formats a JSON number like ECMAScript's Number.toString, as
+// JCS requires.
+func canonicalNumber(number json.Number) (string, error) {
+	f, err := strconv.ParseFloat(string(number), 64)
+	if err != nil || math.IsInf(f, 0) {
+		return "", fmt.Errorf("nup: cannot canonicalize number %s", number)
+	}
+	if f == 0 {
+		// Includes negative zero.
+		return "0", nil
+	}
+	var sign string
+	if f < 0 {
+		sign = "-"
+		f = -f
+	}
+	// Format the shortest digits that round-trip as d.ddde±n.
+	mantissa, exponent, _ := strings.Cut(strconv.FormatFloat(f, 'e', -1, 64), "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	exp, _ := strconv.Atoi(exponent)
+	// point is the position of the decimal point relative to the digits.
+	point := exp + 1
+	switch {
+	case len(digits) <= point && point <= 21:
+		return sign + digits + strings.Repeat("0", point-len(digits)), nil
+	case 0 < point && point <= 21:
+		return sign + digits[:point] + "." + digits[point:], nil
+	case -6 < point && point <= 0:
+		return sign + "0." + strings.Repeat("0", -point) + digits, nil
+	}
+	var builder strings.Builder
+	builder.WriteString(sign)
+	builder.WriteString(digits[:1])
+	if len(digits) > 1 {
+		builder.WriteByte('.')
+		builder.WriteString(digits[1:])
+	}
+	builder.WriteByte('e')
+	if exp >= 0 {
+		builder.WriteByte('+')
+	}
+	builder.WriteString(strconv.Itoa(exp))
+	return builder.String(), nil
+}
+
+// appendCanonicalString writes s to buf as a JSON string, escaping only the
+// characters that JCS requires: quotation marks, backslashes, and control
+// characters.
+func appendCanonicalString(buf *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hex[r>>4])
+				buf.WriteByte(hex[r&0xf])
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// lessUTF16 reports whether a sorts before b when both are compared as
+// sequences of UTF-16 code units.
+func lessUTF16(a, b string) bool {
+	var (
+		units1 = utf16.Encode([]rune(a))
+		units2 = utf16.Encode([]rune(b))
+	)
+	for i := 0; i < len(units1) && i < len(units2); i++ {
+		if units1[i] != units2[i] {
+			return units1[i] < units2[i]
+		}
+	}
+	return len(units1) < len(units2)
+}
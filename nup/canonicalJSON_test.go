@@ -0,0 +1,161 @@
+package nup
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/nicheinc/expect"
+)
+
+type testOmitzeroPatch struct {
+	Email Update[string] `json:"email,omitzero"`
+	Bio   Update[string] `json:"bio,omitzero"`
+	Admin Update[bool]   `json:"admin,omitzero"`
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{
+			// The example from RFC 8785, section 3.2.2.
+			name:     "RFC8785Example",
+			value:    json.RawMessage(`{"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001], "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/", "literals": [null, true, false]}`),
+			expected: `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`,
+		},
+		{
+			// The example from RFC 8785, section 3.2.3.
+			name:     "RFC8785Sorting",
+			value:    json.RawMessage(`{"\u20ac":"Euro Sign","\r":"Carriage Return","\ufb33":"Hebrew Letter Dalet With Dagesh","1":"One","\ud83d\ude00":"Emoji: Grinning Face","\u0080":"Control","\u00f6":"Latin Small Letter O With Diaeresis"}`),
+			expected: "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"ö\":\"Latin Small Letter O With Diaeresis\",\"€\":\"Euro Sign\",\"😀\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}",
+		},
+		{
+			name:     "HTMLCharacters",
+			value:    "<a&b>",
+			expected: `"<a&b>"`,
+		},
+		{
+			name:     "Map",
+			value:    map[string]int{"b": 2, "a": 1},
+			expected: `{"a":1,"b":2}`,
+		},
+		{
+			name: "PatchNoopsOmitted",
+			value: testPatch{
+				Name: Set("Alice"),
+				Tags: SliceRemove[int](),
+				Note: "note",
+			},
+			expected: `{"name":"Alice","note":"note","tags":null}`,
+		},
+		{
+			name: "NestedPatch",
+			value: &testPatch{
+				Age:     Remove[int](),
+				Address: testAddressPatch{City: Set("Paris")},
+			},
+			expected: `{"address":{"city":"Paris"},"age":null,"note":""}`,
+		},
+		{
+			name: "OmitzeroPatch",
+			value: testOmitzeroPatch{
+				Email: Set("a@example.com"),
+			},
+			expected: `{"email":"a@example.com"}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, err := CanonicalJSON(testCase.value)
+			expect.ErrorNil(t, err)
+			expect.Equal(t, string(actual), testCase.expected)
+		})
+	}
+}
+
+func TestCanonicalJSON_Errors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value interface{}
+	}{
+		{
+			name:  "Unmarshallable",
+			value: func() {},
+		},
+		{
+			name:  "NumberOutOfRange",
+			value: json.RawMessage(`1e400`),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := CanonicalJSON(testCase.value)
+			expect.ErrorNonNil(t, err)
+		})
+	}
+}
+
+func TestCanonicalNumber(t *testing.T) {
+	// The examples from RFC 8785, appendix B, along with a few others.
+	testCases := []struct {
+		number   string
+		expected string
+	}{
+		{"0", "0"},
+		{"-0", "0"},
+		{"5e-324", "5e-324"},
+		{"-5e-324", "-5e-324"},
+		{"1.7976931348623157e308", "1.7976931348623157e+308"},
+		{"-1.7976931348623157e308", "-1.7976931348623157e+308"},
+		{"9007199254740992", "9007199254740992"},
+		{"-9007199254740992", "-9007199254740992"},
+		{"295147905179352825856", "295147905179352830000"},
+		{"9.999999999999997e22", "9.999999999999997e+22"},
+		{"1e23", "1e+23"},
+		{"1e21", "1e+21"},
+		{"999999999999999900000", "999999999999999900000"},
+		{"0.000001", "0.000001"},
+		{"9.999999999999997e-7", "9.999999999999997e-7"},
+		{"1e-7", "1e-7"},
+		{"333333333.3333332", "333333333.3333332"},
+		{"4.50", "4.5"},
+		{"100", "100"},
+		{"1.5e2", "150"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.number, func(t *testing.T) {
+			actual, err := canonicalNumber(json.Number(testCase.number))
+			expect.ErrorNil(t, err)
+			expect.Equal(t, actual, testCase.expected)
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	hash, err := Hash(json.RawMessage(`{"b": 2, "a": 1}`))
+	expect.ErrorNil(t, err)
+	// The SHA-256 digest of {"a":1,"b":2}.
+	expect.Equal(t, hex.EncodeToString(hash[:]), "43258cff783fe7036d8a43033f830adfc60ec037382473548ac742b888292777")
+
+	// Patches making the same changes have the same hash, regardless of
+	// struct tags.
+	var (
+		patch = testOmitzeroPatch{Email: Set("a@example.com"), Bio: Remove[string]()}
+		other = struct {
+			Email Update[string] `json:"email"`
+			Bio   Update[string] `json:"bio"`
+			Admin Update[bool]   `json:"admin"`
+		}{Email: Set("a@example.com"), Bio: Remove[string]()}
+	)
+	patchHash, err := Hash(patch)
+	expect.ErrorNil(t, err)
+	otherHash, err := Hash(&other)
+	expect.ErrorNil(t, err)
+	expect.Equal(t, patchHash, otherHash)
+}
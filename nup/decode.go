@@ -0,0 +1,216 @@
+package nup
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+var (
+	// ErrNull indicates a null value for a field marked `nup:"nonnull"`.
+	ErrNull = errors.New("null not allowed")
+	// ErrRequired indicates a missing field marked `nup:"required"`.
+	ErrRequired = errors.New("missing required field")
+	// ErrImmutable indicates a value for a field marked `nup:"immutable"`.
+	ErrImmutable = errors.New("field is immutable")
+	// ErrUnknownKey indicates a key that doesn't correspond to any field, when
+	// DecodeOptions.DisallowUnknownKeys is set.
+	ErrUnknownKey = errors.New("unknown key")
+	// ErrDuplicateKey indicates a key that appears more than once in an
+	// object, when DecodeOptions.DisallowDuplicateKeys is set.
+	ErrDuplicateKey = errors.New("duplicate key")
+)
+
+// DecodeOptions configures Decode.
+type DecodeOptions struct {
+	// DisallowUnknownKeys causes Decode to reject object keys that don't
+	// correspond to a field of the patch struct.
+	DisallowUnknownKeys bool
+	// DisallowDuplicateKeys causes Decode to reject objects in which more than
+	// one key corresponds to the same field. (By default, the last one wins,
+	// as with json.Unmarshal.)
+	DisallowDuplicateKeys bool
+}
+
+// Decode unmarshals a JSON object into the patch struct pointed to by patch,
+// much like json.Unmarshal, but enforces the constraints expressed by "nup"
+// struct tags, as well as those in opts. The tag options are:
+//   - nonnull: the field may not be null; i.e. it may not be removed.
+//   - required: the field must be present, though it may be null.
+//   - immutable: the field must not be present; i.e. it must be a no-op.
+//
+// For example:
+//
+//	type UserPatch struct {
+//		Email nup.Update[string] `json:"email" nup:"required,nonnull"`
+//		Phone nup.Update[string] `json:"phone"`
+//		ID    nup.Update[int]    `json:"id" nup:"immutable"`
+//	}
+//
+// Struct fields that are themselves patch structs (i.e., contain Update or
+// SliceUpdate fields) are decoded recursively, with the same rules. Pointers to
+// patch structs aren't supported: Decode returns an error, without decoding
+// data, if the patch struct has such a field.
+//
+// If data is a well-formed JSON object, Decode checks every field rather than
+// stopping at the first problem, and returns any problems found as a
+// FieldErrors, each identifying the offending field by its JSON pointer, e.g.
+// "/address/city".
+func Decode(data []byte, patch interface{}, opts DecodeOptions) error {
+	v := reflect.ValueOf(patch)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("nup: Decode requires a non-nil pointer to a struct, not %T", patch)
+	}
+	if err := checkPatchType(v.Type().Elem()); err != nil {
+		return err
+	}
+	members, err := objectMembers(data)
+	if err != nil {
+		return fmt.Errorf("nup: %w", err)
+	}
+	var errs FieldErrors
+	decodeObject(members, v.Elem(), "", "", opts, &errs)
+	return errs.Err()
+}
+
+// decodeObject decodes the members of a JSON object into the struct v, located
+// at the JSON pointer path and the Go field name prefix. It appends any
+// problems to errs.
+func decodeObject(members []member, v reflect.Value, path, prefix string, opts DecodeOptions, errs *FieldErrors) {
+	var (
+		fields  = structFields(v.Type())
+		present = map[string]bool{}
+	)
+	for _, member := range members {
+		isNull := bytes.Equal(member.value, []byte("null"))
+		op := OpSet
+		if isNull {
+			op = OpRemove
+		}
+		field, ok := lookupField(fields, member.key)
+		if !ok {
+			if opts.DisallowUnknownKeys {
+				*errs = append(*errs, &FieldError{
+					Path: appendPointer(path, member.key),
+					Op:   op,
+					Err:  ErrUnknownKey,
+				})
+			}
+			continue
+		}
+		fieldError := func(err error) *FieldError {
+			return &FieldError{
+				Path:  appendPointer(path, field.key),
+				Field: prefix + field.Name,
+				Op:    op,
+				Err:   err,
+			}
+		}
+		if present[field.key] && opts.DisallowDuplicateKeys {
+			*errs = append(*errs, fieldError(ErrDuplicateKey))
+			continue
+		}
+		present[field.key] = true
+
+		if err := field.opts.check(op); err != nil {
+			*errs = append(*errs, fieldError(err))
+			continue
+		}
+		fieldValue := v.FieldByIndex(field.Index)
+		if field.kind == patchKind {
+			if isNull {
+				// A patch struct can't represent the removal of the object
+				// as a whole.
+				*errs = append(*errs, fieldError(ErrNull))
+				continue
+			}
+			nestedMembers, err := objectMembers(member.value)
+			if err != nil {
+				*errs = append(*errs, fieldError(err))
+				continue
+			}
+			decodeObject(nestedMembers, fieldValue, appendPointer(path, field.key), prefix+field.Name+".", opts, errs)
+			continue
+		}
+		if err := json.Unmarshal(member.value, fieldValue.Addr().Interface()); err != nil {
+			*errs = append(*errs, fieldError(err))
+		}
+	}
+	for _, field := range fields {
+		if present[field.key] {
+			continue
+		}
+		if err := field.opts.check(OpNoop); err != nil {
+			*errs = append(*errs, &FieldError{
+				Path:  appendPointer(path, field.key),
+				Field: prefix + field.Name,
+				Op:    OpNoop,
+				Err:   err,
+			})
+		}
+	}
+}
+
+// member is a key-value pair from a JSON object.
+type member struct {
+	key   string
+	value json.RawMessage
+}
+
+// objectMembers returns the members of the JSON object in data, in order and
+// including any duplicate keys.
+func objectMembers(data []byte) ([]member, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('{') {
+		return nil, fmt.Errorf("cannot decode %s into a patch struct", describeToken(token))
+	}
+	var members []member
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		members = append(members, member{
+			key:   token.(string),
+			value: value,
+		})
+	}
+	// Consume the closing brace and ensure nothing follows it.
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("invalid data after top-level value")
+	}
+	return members, nil
+}
+
+// describeToken describes the JSON value beginning with token, for use in
+// error messages.
+func describeToken(token json.Token) string {
+	switch token := token.(type) {
+	case json.Delim:
+		return "array"
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64, json.Number:
+		return "number"
+	case string:
+		return "string"
+	default:
+		return fmt.Sprintf("%v", token)
+	}
+}
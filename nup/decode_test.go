@@ -0,0 +1,190 @@
+package nup
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/nicheinc/expect"
+)
+
+type testAddressPatch struct {
+	City Update[string] `json:"city" nup:"nonnull"`
+	Zip  Update[string] `json:"zip"`
+}
+
+type testPatch struct {
+	ID      Update[int]      `json:"id" nup:"immutable"`
+	Name    Update[string]   `json:"name" nup:"required,nonnull"`
+	Age     Update[int]      `json:"age"`
+	Tags    SliceUpdate[int] `json:"tags" nup:"nonnull"`
+	Address testAddressPatch `json:"address"`
+	Note    string           `json:"note"`
+	Skipped Update[int]      `json:"-"`
+}
+
+func TestDecode(t *testing.T) {
+	testCases := []struct {
+		name       string
+		json       string
+		opts       DecodeOptions
+		expected   testPatch
+		errorCheck expect.ErrorCheck
+	}{
+		{
+			name: "Valid",
+			json: `{"name":"Alice","age":null,"tags":[1,2],"address":{"zip":null},"note":"hi"}`,
+			expected: testPatch{
+				Name:    Set("Alice"),
+				Age:     Remove[int](),
+				Tags:    SliceRemoveOrSet([]int{1, 2}),
+				Address: testAddressPatch{Zip: Remove[string]()},
+				Note:    "hi",
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "CaseInsensitiveKey",
+			json: `{"NAME":"Alice"}`,
+			expected: testPatch{
+				Name: Set("Alice"),
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "NotAnObject",
+			json:       `[]`,
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name:       "TrailingData",
+			json:       `{"name":"Alice"} {}`,
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name:       "InvalidValue",
+			json:       `{"name":"Alice","age":"old"}`,
+			errorCheck: expect.ErrorAs[*json.UnmarshalTypeError](),
+		},
+		{
+			name:       "Immutable",
+			json:       `{"name":"Alice","id":1}`,
+			errorCheck: expect.ErrorIs(ErrImmutable),
+		},
+		{
+			name:       "Required",
+			json:       `{"age":1}`,
+			errorCheck: expect.ErrorIs(ErrRequired),
+		},
+		{
+			name:       "NonnullUpdate",
+			json:       `{"name":null}`,
+			errorCheck: expect.ErrorIs(ErrNull),
+		},
+		{
+			name:       "NonnullSliceUpdate",
+			json:       `{"name":"Alice","tags":null}`,
+			errorCheck: expect.ErrorIs(ErrNull),
+		},
+		{
+			name:       "NonnullNested",
+			json:       `{"name":"Alice","address":{"city":null}}`,
+			errorCheck: expect.ErrorIs(ErrNull),
+		},
+		{
+			name:       "NullNestedPatch",
+			json:       `{"name":"Alice","address":null}`,
+			errorCheck: expect.ErrorIs(ErrNull),
+		},
+		{
+			name: "UnknownKeyAllowed",
+			json: `{"name":"Alice","unknown":1}`,
+			expected: testPatch{
+				Name: Set("Alice"),
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "UnknownKeyDisallowed",
+			json:       `{"name":"Alice","unknown":1}`,
+			opts:       DecodeOptions{DisallowUnknownKeys: true},
+			errorCheck: expect.ErrorIs(ErrUnknownKey),
+		},
+		{
+			name: "SkippedKeyDisallowed",
+			json: `{"name":"Alice","Skipped":1}`,
+			opts: DecodeOptions{
+				DisallowUnknownKeys: true,
+			},
+			errorCheck: expect.ErrorIs(ErrUnknownKey),
+		},
+		{
+			name: "DuplicateKeyAllowed",
+			json: `{"name":"Alice","age":1,"Age":2}`,
+			expected: testPatch{
+				Name: Set("Alice"),
+				Age:  Set(2),
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "DuplicateKeyDisallowed",
+			json:       `{"name":"Alice","age":1,"Age":2}`,
+			opts:       DecodeOptions{DisallowDuplicateKeys: true},
+			errorCheck: expect.ErrorIs(ErrDuplicateKey),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var actual testPatch
+			err := Decode([]byte(testCase.json), &actual, testCase.opts)
+			testCase.errorCheck(t, err)
+			if err == nil {
+				expect.Equal(t, actual, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestDecode_FieldErrors(t *testing.T) {
+	var (
+		data = `{"id":1,"age":"old","address":{"city":null},"extra":null}`
+		opts = DecodeOptions{DisallowUnknownKeys: true}
+	)
+	var patch testPatch
+	err := Decode([]byte(data), &patch, opts)
+	var fieldErrors FieldErrors
+	if !errors.As(err, &fieldErrors) {
+		t.Fatalf("Expected FieldErrors, got %T", err)
+	}
+	actual := make([]FieldError, len(fieldErrors))
+	for i, fieldError := range fieldErrors {
+		actual[i] = *fieldError
+	}
+	expected := []FieldError{
+		{Path: "/id", Field: "ID", Op: OpSet, Err: ErrImmutable},
+		{Path: "/age", Field: "Age", Op: OpSet, Err: actual[1].Err},
+		{Path: "/address/city", Field: "Address.City", Op: OpRemove, Err: ErrNull},
+		{Path: "/extra", Op: OpRemove, Err: ErrUnknownKey},
+		{Path: "/name", Field: "Name", Op: OpNoop, Err: ErrRequired},
+	}
+	expect.Equal(t, actual, expected, cmpopts.EquateErrors())
+	expect.ErrorAs[*json.UnmarshalTypeError]()(t, actual[1].Err)
+}
+
+func TestDecode_PointerToPatch(t *testing.T) {
+	var patch testPointerPatch
+	err := Decode([]byte(`{"address":{"city":null}}`), &patch, DecodeOptions{})
+	expect.ErrorNonNil(t, err)
+	var errs FieldErrors
+	expect.Equal(t, errors.As(err, &errs), false)
+	expect.Equal(t, patch, testPointerPatch{})
+}
+
+func TestDecode_NonStruct(t *testing.T) {
+	var patch int
+	err := Decode([]byte(`{}`), &patch, DecodeOptions{})
+	expect.ErrorNonNil(t, err)
+}
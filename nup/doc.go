@@ -28,6 +28,55 @@ fields of type nup.Update or nup.SliceUpdate. This will ensure that if the field
 is a no-op, it's correctly omitted from the JSON output. (If the omitzero tag is
 absent, the field will be marshalled as null.)
 
+Where a no-op can't be represented by omitting a field, e.g. in an array of
+updates, convert the updates to Envelope or SliceEnvelope, which marshal their
+operations explicitly, as in {"op":"remove"}.
+
+CanonicalJSON marshals a patch in the canonical form defined by RFC 8785, with
+sorted keys and no no-ops, so that patches making the same changes have
+identical encodings, and Hash digests that encoding, e.g. to recognize retried
+requests.
+
+Update and SliceUpdate also implement encoding.TextMarshaler and
+encoding.TextUnmarshaler, for use in CSV files, environment variables, query
+strings, and the like. Since text can't omit a value the way JSON can, a no-op
+is represented by an explicit token, like a removal. See TextOptions.
+
+They implement the Marshaler and Unmarshaler interfaces of yaml.v2-style
+libraries as well, without depending on any of them. Note, however, that
+yaml.v2 and yaml.v3 never pass null to an Unmarshaler, so with them null
+decodes as a no-op rather than a removal; see Update.UnmarshalYAML. For XML,
+they implement xml.Marshaler and xml.Unmarshaler, representing a removal as an
+element with the attribute xsi:nil="true" and a no-op as an absent element.
+Finally, they implement encoding.BinaryMarshaler and gob.GobEncoder (and the
+corresponding decoding interfaces) with a compact binary form that preserves
+all three operations.
+
+# Decoding
+
+json.Unmarshal decodes null as a removal for any nup field. To restrict which
+operations a client may request, use Decode with "nup" struct tags, which can
+reject null values, missing fields, or changes to immutable fields, as well as
+unknown or duplicate keys. DecodeValues does the same for URL query
+parameters and form values, and EncodeValues produces them. For command-line
+tools, Flag and RegisterFlags define flags that distinguish leaving a field
+alone, clearing it, and setting it, and FromEnv reads a patch from
+environment variables.
+
+# Applying
+
+ApplyPatch applies a patch struct's updates to the matching fields of a model
+struct, in place; CopyOnWrite copies a model that may be shared so that a patch
+can be applied to the copy instead. DiffPatch drops the updates that wouldn't
+change a model. Conversely,
+DiffModels computes the patch that transforms one model into another.
+CheckRules checks constraints that span multiple fields, such as Requires or
+AtLeastOneRemains, against the state the model would be in after applying the
+patch. To guard against mass assignment, Mask rejects, and Sanitize discards,
+changes to fields outside a FieldSet, which may be built from "role=" options
+in "nup" struct tags using FieldSetForRole. SquashPatch combines successive
+patches into one, which package nupconfig uses to layer configuration sources.
+
 [json.Marshal]: https://pkg.go.dev/encoding/json#Marshal
 */
 package nup
@@ -0,0 +1,120 @@
+package nup
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// EnvOptions configures FromEnv.
+type EnvOptions struct {
+	// Null is the value representing a removal. If it's empty, the default,
+	// a variable set to the empty string removes its field. Otherwise, only
+	// the given sentinel, e.g. "null", removes the field, and an empty value
+	// is unmarshalled like any other, e.g. as the empty string or, for a
+	// SliceUpdate, an empty slice.
+	Null string
+	// Separator separates the elements of a SliceUpdate's value. If it's
+	// empty, the default, elements are separated by commas.
+	Separator string
+	// LookupEnv looks up environment variables. If it's nil, the default,
+	// os.LookupEnv is used.
+	LookupEnv func(key string) (string, bool)
+}
+
+// envCodec is implemented by Update and SliceUpdate pointers, so that they can
+// be decoded from environment variables.
+type envCodec interface {
+	decodeEnv(value string, opts EnvOptions) error
+}
+
+// FromEnv fills the patch struct pointed to by patch from environment
+// variables using the default EnvOptions. See EnvOptions.FromEnv.
+func FromEnv(prefix string, patch interface{}) error {
+	return EnvOptions{}.FromEnv(prefix, patch)
+}
+
+// FromEnv fills the patch struct pointed to by patch from environment
+// variables. Each field's variable is named by the prefix, if it's non-empty,
+// followed by an underscore and the field's JSON key in upper snake case. The
+// fields of nested patch structs are named by their keys joined with
+// underscores. For example, with the prefix "APP", the field with JSON pointer
+// "/address/postalCode" is read from APP_ADDRESS_POSTAL_CODE.
+//
+// An unset variable leaves its field unchanged, e.g. as a no-op. A variable
+// set to the null value (see EnvOptions.Null) removes its field, and any other
+// value sets it: the value of an Update[T] is unmarshalled like UnmarshalText,
+// and the value of a SliceUpdate[T] is split on the separator and each element
+// unmarshalled likewise.
+//
+// Like Decode, FromEnv enforces the constraints expressed by "nup" struct
+// tags, and returns any problems found as a FieldErrors.
+func (o EnvOptions) FromEnv(prefix string, patch interface{}) error {
+	v := reflect.ValueOf(patch)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("nup: FromEnv requires a non-nil pointer to a struct, not %T", patch)
+	}
+	if o.Separator == "" {
+		o.Separator = ","
+	}
+	if o.LookupEnv == nil {
+		o.LookupEnv = os.LookupEnv
+	}
+	var errs FieldErrors
+	for _, field := range patchFields(patch) {
+		var (
+			name       = envName(prefix, field.path)
+			value, set = o.LookupEnv(name)
+			op         = OpNoop
+		)
+		switch {
+		case !set:
+		case value == o.Null:
+			op = OpRemove
+		default:
+			op = OpSet
+		}
+		fieldError := func(err error) *FieldError {
+			return &FieldError{
+				Path:  field.path,
+				Field: field.name,
+				Op:    op,
+				Err:   fmt.Errorf("%s: %w", name, nestedError{err}),
+			}
+		}
+		if err := field.opts.check(op); err != nil {
+			errs = append(errs, fieldError(err))
+			continue
+		}
+		if op == OpNoop {
+			continue
+		}
+		codec := field.value.Addr().Interface().(envCodec)
+		if err := codec.decodeEnv(value, o); err != nil {
+			errs = append(errs, fieldError(err))
+		}
+	}
+	return errs.Err()
+}
+
+// envName returns the name of the environment variable for the field of a
+// patch struct at the given JSON pointer.
+func envName(prefix, pointer string) string {
+	keys := pointerKeys(pointer)
+	for i, key := range keys {
+		keys[i] = strings.ToUpper(snakeCase(key))
+	}
+	name := strings.Join(keys, "_")
+	// Replace characters that aren't conventional in variable names.
+	name = strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	if prefix != "" {
+		name = prefix + "_" + name
+	}
+	return name
+}
@@ -0,0 +1,172 @@
+package nup
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/nicheinc/expect"
+)
+
+type testEnvPatch struct {
+	Name       Update[string]      `json:"name"`
+	Age        Update[int]         `json:"age"`
+	Tags       SliceUpdate[string] `json:"tags"`
+	PostalCode Update[string]      `json:"postalCode"`
+	Address    testAddressPatch    `json:"address"`
+}
+
+func lookupMap(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestEnvOptions_FromEnv(t *testing.T) {
+	testCases := []struct {
+		name       string
+		env        map[string]string
+		opts       EnvOptions
+		expected   testEnvPatch
+		errorCheck expect.ErrorCheck
+	}{
+		{
+			name:       "Unset",
+			env:        map[string]string{},
+			expected:   testEnvPatch{},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "SetAndRemove",
+			env: map[string]string{
+				"APP_NAME":         "Alice",
+				"APP_AGE":          "",
+				"APP_TAGS":         "a,b",
+				"APP_POSTAL_CODE":  "12345",
+				"APP_ADDRESS_CITY": "Paris",
+				"APP_ADDRESS_ZIP":  "",
+				"NAME":             "ignored",
+			},
+			expected: testEnvPatch{
+				Name:       Set("Alice"),
+				Age:        Remove[int](),
+				Tags:       SliceRemoveOrSet([]string{"a", "b"}),
+				PostalCode: Set("12345"),
+				Address:    testAddressPatch{City: Set("Paris"), Zip: Remove[string]()},
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "SliceRemove",
+			env: map[string]string{
+				"APP_TAGS": "",
+			},
+			expected: testEnvPatch{
+				Tags: SliceRemove[string](),
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "NullSentinel",
+			env: map[string]string{
+				"APP_NAME": "",
+				"APP_AGE":  "null",
+				"APP_TAGS": "",
+			},
+			opts: EnvOptions{Null: "null"},
+			expected: testEnvPatch{
+				Name: Set(""),
+				Age:  Remove[int](),
+				Tags: SliceRemoveOrSet([]string{}),
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "Separator",
+			env: map[string]string{
+				"APP_TAGS": "a,b;c",
+			},
+			opts: EnvOptions{Separator: ";"},
+			expected: testEnvPatch{
+				Tags: SliceRemoveOrSet([]string{"a,b", "c"}),
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "InvalidValue",
+			env: map[string]string{
+				"APP_AGE": "old",
+			},
+			errorCheck: expect.ErrorIs(strconv.ErrSyntax),
+		},
+		{
+			name: "Nonnull",
+			env: map[string]string{
+				"APP_ADDRESS_CITY": "",
+			},
+			errorCheck: expect.ErrorIs(ErrNull),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var actual testEnvPatch
+			opts := testCase.opts
+			opts.LookupEnv = lookupMap(testCase.env)
+			err := opts.FromEnv("APP", &actual)
+			testCase.errorCheck(t, err)
+			if err == nil {
+				expect.Equal(t, actual, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("NAME", "Alice")
+	t.Setenv("AGE", "")
+	var actual testEnvPatch
+	err := FromEnv("", &actual)
+	expect.ErrorNil(t, err)
+	expect.Equal(t, actual, testEnvPatch{
+		Name: Set("Alice"),
+		Age:  Remove[int](),
+	})
+}
+
+func TestFromEnv_FieldErrors(t *testing.T) {
+	var patch testEnvPatch
+	err := EnvOptions{LookupEnv: lookupMap(map[string]string{"APP_AGE": "old"})}.FromEnv("APP", &patch)
+	var fieldErrors FieldErrors
+	if !errors.As(err, &fieldErrors) {
+		t.Fatalf("Expected FieldErrors, got %T", err)
+	}
+	expect.Equal(t, len(fieldErrors), 1)
+	expect.Equal(t, fieldErrors[0].Error(), `nup: /age: cannot set: APP_AGE: cannot unmarshal "old" into int: invalid syntax`)
+}
+
+func TestFromEnv_NotPointer(t *testing.T) {
+	err := FromEnv("APP", testEnvPatch{})
+	expect.ErrorNonNil(t, err)
+}
+
+func TestEnvName(t *testing.T) {
+	testCases := []struct {
+		prefix   string
+		pointer  string
+		expected string
+	}{
+		{prefix: "", pointer: "/name", expected: "NAME"},
+		{prefix: "APP", pointer: "/postalCode", expected: "APP_POSTAL_CODE"},
+		{prefix: "APP", pointer: "/address/city", expected: "APP_ADDRESS_CITY"},
+		{prefix: "APP", pointer: "/dash-key", expected: "APP_DASH_KEY"},
+		{prefix: "APP", pointer: "/a~1b", expected: "APP_A_B"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.expected, func(t *testing.T) {
+			expect.Equal(t, envName(testCase.prefix, testCase.pointer), testCase.expected)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package nup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Envelope is an Update that marshals to and from JSON with its operation made
+// explicit, as an object with an "op" key holding the operation's name (see
+// Operation.String) and, for a set operation, a "value" key holding the value:
+//
+//	{"op":"set","value":42}
+//	{"op":"remove"}
+//	{"op":"no-op"}
+//
+// Unlike an Update, whose no-op is represented by the absence of its field, an
+// Envelope can represent all three operations wherever it appears, e.g. in an
+// array, as a map value, or in an event payload. When unmarshalling, "noop" is
+// accepted as an alias for "no-op".
+//
+// Envelope and Update are convertible to each other: use Envelope[T](u) to wrap
+// an update and Update to unwrap it.
+type Envelope[T comparable] Update[T]
+
+// Update returns the update the envelope wraps.
+func (e Envelope[T]) Update() Update[T] {
+	return Update[T](e)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (e Envelope[T]) MarshalJSON() ([]byte, error) {
+	return marshalEnvelope(e.op, e.value)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (e *Envelope[T]) UnmarshalJSON(data []byte) error {
+	op, value, err := unmarshalEnvelope(data)
+	if err != nil {
+		return err
+	}
+	if op != OpSet {
+		*e = Envelope[T]{op: op}
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(value, &v); err != nil {
+		return err
+	}
+	*e = Envelope[T](Set(v))
+	return nil
+}
+
+// SliceEnvelope is a SliceUpdate that marshals to and from JSON with its
+// operation made explicit, like Envelope:
+//
+//	{"op":"set","value":[1,2]}
+//	{"op":"remove"}
+//	{"op":"no-op"}
+//
+// SliceEnvelope and SliceUpdate are convertible to each other: use
+// SliceEnvelope[T](u) to wrap an update and Update to unwrap it.
+type SliceEnvelope[T comparable] SliceUpdate[T]
+
+// Update returns the update the envelope wraps.
+func (e SliceEnvelope[T]) Update() SliceUpdate[T] {
+	return SliceUpdate[T](e)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (e SliceEnvelope[T]) MarshalJSON() ([]byte, error) {
+	return marshalEnvelope(e.op, e.value)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. A set operation whose value is
+// null sets an empty slice.
+func (e *SliceEnvelope[T]) UnmarshalJSON(data []byte) error {
+	op, value, err := unmarshalEnvelope(data)
+	if err != nil {
+		return err
+	}
+	if op != OpSet {
+		*e = SliceEnvelope[T]{op: op}
+		return nil
+	}
+	var v []T
+	if err := json.Unmarshal(value, &v); err != nil {
+		return err
+	}
+	if v == nil {
+		v = []T{}
+	}
+	*e = SliceEnvelope[T](SliceRemoveOrSet(v))
+	return nil
+}
+
+// envelope is the JSON representation of Envelope and SliceEnvelope.
+type envelope struct {
+	Op    *Operation      `json:"op"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+func marshalEnvelope(op Operation, value interface{}) ([]byte, error) {
+	env := envelope{
+		Op: &op,
+	}
+	if op == OpSet {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		env.Value = data
+	}
+	return json.Marshal(env)
+}
+
+// unmarshalEnvelope returns the operation of the envelope in data and, for a
+// set operation, its raw value.
+func unmarshalEnvelope(data []byte) (Operation, json.RawMessage, error) {
+	var env *envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return 0, nil, err
+	}
+	if env == nil {
+		return 0, nil, errors.New("nup: envelope must be an object, not null")
+	}
+	if env.Op == nil {
+		return 0, nil, errors.New(`nup: envelope is missing "op"`)
+	}
+	op := *env.Op
+	switch {
+	case op == OpSet && env.Value == nil:
+		return 0, nil, errors.New(`nup: envelope for set operation is missing "value"`)
+	case op != OpSet && env.Value != nil:
+		return 0, nil, fmt.Errorf(`nup: envelope for %s operation must not have "value"`, op)
+	}
+	return op, env.Value, nil
+}
@@ -0,0 +1,228 @@
+package nup
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/nicheinc/expect"
+)
+
+// Ensure implementation of the json interfaces.
+var (
+	_ json.Marshaler   = Envelope[int]{}
+	_ json.Unmarshaler = &Envelope[int]{}
+	_ json.Marshaler   = SliceEnvelope[int]{}
+	_ json.Unmarshaler = &SliceEnvelope[int]{}
+)
+
+func TestEnvelope_MarshalJSON(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{
+			name:     "Noop",
+			value:    Envelope[int](Noop[int]()),
+			expected: `{"op":"no-op"}`,
+		},
+		{
+			name:     "Remove",
+			value:    Envelope[int](Remove[int]()),
+			expected: `{"op":"remove"}`,
+		},
+		{
+			name:     "Set",
+			value:    Envelope[int](Set(0)),
+			expected: `{"op":"set","value":0}`,
+		},
+		{
+			name:     "SliceNoop",
+			value:    SliceEnvelope[int](SliceNoop[int]()),
+			expected: `{"op":"no-op"}`,
+		},
+		{
+			name:     "SliceRemove",
+			value:    SliceEnvelope[int](SliceRemove[int]()),
+			expected: `{"op":"remove"}`,
+		},
+		{
+			name:     "SliceSet",
+			value:    SliceEnvelope[int](SliceRemoveOrSet([]int{1, 2})),
+			expected: `{"op":"set","value":[1,2]}`,
+		},
+		{
+			name:     "SliceSetEmpty",
+			value:    SliceEnvelope[int](SliceRemoveOrSet([]int{})),
+			expected: `{"op":"set","value":[]}`,
+		},
+		{
+			name: "Array",
+			value: []Envelope[string]{
+				Envelope[string](Noop[string]()),
+				Envelope[string](Remove[string]()),
+				Envelope[string](Set("a")),
+			},
+			expected: `[{"op":"no-op"},{"op":"remove"},{"op":"set","value":"a"}]`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, err := json.Marshal(testCase.value)
+			expect.ErrorNil(t, err)
+			expect.Equal(t, string(actual), testCase.expected)
+		})
+	}
+}
+
+func TestEnvelope_UnmarshalJSON(t *testing.T) {
+	testCases := []struct {
+		name       string
+		json       string
+		expected   Update[int]
+		errorCheck expect.ErrorCheck
+	}{
+		{
+			name:       "Noop",
+			json:       `{"op":"no-op"}`,
+			expected:   Noop[int](),
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "NoopAlias",
+			json:       `{"op":"noop"}`,
+			expected:   Noop[int](),
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "Remove",
+			json:       `{"op":"remove"}`,
+			expected:   Remove[int](),
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "Set",
+			json:       `{"value":0,"op":"set"}`,
+			expected:   Set(0),
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "Null",
+			json:       `null`,
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name:       "NotObject",
+			json:       `42`,
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name:       "MissingOp",
+			json:       `{"value":1}`,
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name:       "UnknownOp",
+			json:       `{"op":"replace","value":1}`,
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name:       "SetMissingValue",
+			json:       `{"op":"set"}`,
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name:       "RemoveWithValue",
+			json:       `{"op":"remove","value":1}`,
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name:       "InvalidValue",
+			json:       `{"op":"set","value":"one"}`,
+			errorCheck: expect.ErrorNonNil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var actual Envelope[int]
+			err := json.Unmarshal([]byte(testCase.json), &actual)
+			testCase.errorCheck(t, err)
+			if err == nil {
+				expect.Equal(t, actual.Update(), testCase.expected)
+			}
+		})
+	}
+}
+
+func TestSliceEnvelope_UnmarshalJSON(t *testing.T) {
+	testCases := []struct {
+		name       string
+		json       string
+		expected   SliceUpdate[int]
+		errorCheck expect.ErrorCheck
+	}{
+		{
+			name:       "Noop",
+			json:       `{"op":"noop"}`,
+			expected:   SliceNoop[int](),
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "Remove",
+			json:       `{"op":"remove"}`,
+			expected:   SliceRemove[int](),
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "Set",
+			json:       `{"op":"set","value":[1,2]}`,
+			expected:   SliceRemoveOrSet([]int{1, 2}),
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "SetNull",
+			json:       `{"op":"set","value":null}`,
+			expected:   SliceRemoveOrSet([]int{}),
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "InvalidValue",
+			json:       `{"op":"set","value":1}`,
+			errorCheck: expect.ErrorNonNil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var actual SliceEnvelope[int]
+			err := json.Unmarshal([]byte(testCase.json), &actual)
+			testCase.errorCheck(t, err)
+			if err == nil {
+				expect.Equal(t, actual.Update(), testCase.expected)
+			}
+		})
+	}
+}
+
+// TestEnvelope_EquivalentForms ensures that the envelope and plain forms
+// decode into the same updates.
+func TestEnvelope_EquivalentForms(t *testing.T) {
+	var (
+		plain struct {
+			Age  Update[int]      `json:"age"`
+			Tags SliceUpdate[int] `json:"tags"`
+		}
+		env struct {
+			Age  Envelope[int]      `json:"age"`
+			Tags SliceEnvelope[int] `json:"tags"`
+		}
+	)
+	err := json.Unmarshal([]byte(`{"age":null,"tags":[1]}`), &plain)
+	expect.ErrorNil(t, err)
+	err = json.Unmarshal([]byte(`{"age":{"op":"remove"},"tags":{"op":"set","value":[1]}}`), &env)
+	expect.ErrorNil(t, err)
+	expect.Equal(t, env.Age.Update(), plain.Age)
+	expect.Equal(t, env.Tags.Update(), plain.Tags)
+}
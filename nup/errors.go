@@ -0,0 +1,89 @@
+package nup
+
+import (
+	"strings"
+)
+
+// FieldError describes a problem with a single field of a patch struct.
+type FieldError struct {
+	// Path is the JSON pointer to the field, e.g. "/address/city".
+	Path string
+	// Field is the Go name of the field, qualified by the names of any
+	// enclosing patch struct fields, e.g. "Address.City". It's empty if the
+	// error concerns input that doesn't correspond to any field.
+	Field string
+	// Op is the operation attempted on the field. It's OpNoop if the error
+	// concerns the field's absence.
+	Op Operation
+	// Err is the underlying error.
+	Err error
+}
+
+// errorPrefix begins the messages of the package's errors.
+const errorPrefix = "nup: "
+
+// nestedError wraps an error of the package for inclusion in the message of
+// another, omitting errorPrefix, which the outer message already begins with.
+type nestedError struct {
+	err error
+}
+
+func (e nestedError) Error() string {
+	return strings.TrimPrefix(e.err.Error(), errorPrefix)
+}
+
+func (e nestedError) Unwrap() error {
+	return e.err
+}
+
+// Error implements error. The message includes the path and, unless it's a
+// no-op, the operation.
+func (e *FieldError) Error() string {
+	var builder strings.Builder
+	builder.WriteString(errorPrefix)
+	builder.WriteString(e.Path)
+	builder.WriteString(": ")
+	if e.Op != OpNoop {
+		builder.WriteString("cannot ")
+		builder.WriteString(e.Op.String())
+		builder.WriteString(": ")
+	}
+	builder.WriteString(nestedError{e.Err}.Error())
+	return builder.String()
+}
+
+// Unwrap returns the underlying error, for use with errors.Is and errors.As.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// FieldErrors collects the problems found in a patch struct, so that they can
+// be reported together rather than one at a time.
+type FieldErrors []*FieldError
+
+// Error implements error, joining the messages of the contained errors.
+func (e FieldErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the contained errors, for use with errors.Is and errors.As.
+func (e FieldErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Err returns e if it contains any errors or else nil. Use it to avoid
+// returning a non-nil error interface holding an empty FieldErrors.
+func (e FieldErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,81 @@
+package nup
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nicheinc/expect"
+)
+
+func TestFieldError_Error(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      *FieldError
+		expected string
+	}{
+		{
+			name: "Noop",
+			err: &FieldError{
+				Path: "/name",
+				Op:   OpNoop,
+				Err:  ErrRequired,
+			},
+			expected: "nup: /name: missing required field",
+		},
+		{
+			name: "Remove",
+			err: &FieldError{
+				Path: "/name",
+				Op:   OpRemove,
+				Err:  ErrNull,
+			},
+			expected: "nup: /name: cannot remove: null not allowed",
+		},
+		{
+			name: "Set",
+			err: &FieldError{
+				Path: "/id",
+				Op:   OpSet,
+				Err:  ErrImmutable,
+			},
+			expected: "nup: /id: cannot set: field is immutable",
+		},
+		{
+			name: "Nested",
+			err: &FieldError{
+				Path: "/age",
+				Op:   OpSet,
+				Err:  errors.New("nup: cannot unmarshal \"old\" into int: invalid syntax"),
+			},
+			expected: `nup: /age: cannot set: cannot unmarshal "old" into int: invalid syntax`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			expect.Equal(t, testCase.err.Error(), testCase.expected)
+		})
+	}
+}
+
+func TestFieldErrors(t *testing.T) {
+	errs := FieldErrors{
+		{Path: "/name", Op: OpRemove, Err: ErrNull},
+		{Path: "/id", Op: OpSet, Err: ErrImmutable},
+	}
+	expect.Equal(t, errs.Error(), "nup: /name: cannot remove: null not allowed; nup: /id: cannot set: field is immutable")
+	expect.ErrorIsAll(ErrNull, ErrImmutable)(t, errs.Err())
+
+	var fieldError *FieldError
+	if !errors.As(errs.Err(), &fieldError) {
+		t.Fatalf("Expected *FieldError")
+	}
+	expect.Equal(t, fieldError.Path, "/name")
+}
+
+func TestFieldErrors_Err(t *testing.T) {
+	var errs FieldErrors
+	expect.ErrorNil(t, errs.Err())
+	errs = append(errs, &FieldError{Path: "/name", Err: ErrNull})
+	expect.ErrorIs(ErrNull)(t, errs.Err())
+}
@@ -0,0 +1,146 @@
+package nup
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+type updateAssigner interface {
+	// assignFrom sets the update according to the given value, as described
+	// by FromFieldMask, returning false if the value's type doesn't match the
+	// update's.
+	assignFrom(field reflect.Value) bool
+	// assignValue sets the update to the given value, as described by
+	// DiffModels, returning false if the value's type doesn't match the
+	// update's.
+	assignValue(field reflect.Value) bool
+}
+
+// FieldMaskPaths returns the paths of the fields that patch, a patch struct or
+// a pointer to one, changes, in the format of a protobuf FieldMask
+// (google.protobuf.FieldMask): each path is the snake_case form of the field's
+// JSON key, qualified by the keys of any enclosing patch structs and separated
+// by dots, e.g. "address.postal_code". Paths are returned in declaration order.
+// Like Fields, FieldMaskPaths skips pointers to patch structs, which aren't
+// supported.
+func FieldMaskPaths(patch interface{}) []string {
+	var paths []string
+	for _, field := range patchFields(patch) {
+		if field.value.Interface().(updateMarshaller).IsChange() {
+			paths = append(paths, fieldMaskPath(field.path))
+		}
+	}
+	return paths
+}
+
+// FromFieldMask sets the fields of the patch struct pointed to by patch
+// according to a protobuf-style field mask, in the format returned by
+// FieldMaskPaths, and full, a struct (or pointer to one) shaped like the
+// corresponding message. Each masked field of patch is set to the value of the
+// field with the same Go name in full, or removed if that value is the zero
+// value: the zero T for a field of type T, nil for *T, or an empty slice for
+// []T. A path naming a nested patch struct masks all of its fields. Fields of
+// patch that aren't masked are left unchanged.
+//
+// For example:
+//
+//	type User struct {
+//		Name  string
+//		Email *string
+//	}
+//
+//	type UserPatch struct {
+//		Name  nup.Update[string] `json:"name"`
+//		Email nup.Update[string] `json:"email"`
+//	}
+//
+//	var patch UserPatch
+//	err := nup.FromFieldMask(req.UpdateMask.Paths, req.User, &patch)
+//
+// FromFieldMask returns an error, without modifying patch, if a path doesn't
+// name a field of patch or if a masked field has no counterpart in full of a
+// matching type.
+func FromFieldMask(paths []string, full, patch interface{}) error {
+	fullValue, ok := structValue(full)
+	if !ok {
+		return fmt.Errorf("nup: FromFieldMask requires a struct, not %T", full)
+	}
+	patchValue := reflect.ValueOf(patch)
+	if patchValue.Kind() != reflect.Pointer || patchValue.IsNil() || patchValue.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("nup: FromFieldMask requires a non-nil pointer to a patch struct, not %T", patch)
+	}
+	fields := patchFields(patch)
+
+	// Check every path and field before assigning any, so that an invalid
+	// mask leaves the patch untouched.
+	type assignment struct {
+		field patchField
+		value reflect.Value
+	}
+	var assignments []assignment
+	for _, path := range paths {
+		matched := false
+		for _, field := range fields {
+			maskPath := fieldMaskPath(field.path)
+			if maskPath != path && !strings.HasPrefix(maskPath, path+".") {
+				continue
+			}
+			matched = true
+			fieldType, ok := modelFieldType(fullValue.Type(), field.name)
+			if !ok {
+				return fmt.Errorf("nup: %s has no field %s", fullValue.Type(), field.name)
+			}
+			value, ok := lookupModelField(fullValue, field.name, false)
+			if !ok {
+				// An enclosing struct pointer is nil.
+				value = reflect.Zero(fieldType)
+			}
+			check := reflect.New(field.Type)
+			if !check.Interface().(updateAssigner).assignFrom(value) {
+				return fmt.Errorf("nup: cannot assign %s from field %s of type %s", field.Type, field.name, fieldType)
+			}
+			assignments = append(assignments, assignment{field, value})
+		}
+		if !matched {
+			return fmt.Errorf("nup: field mask path %q doesn't name a field of %s", path, patchValue.Elem().Type())
+		}
+	}
+	for _, a := range assignments {
+		a.field.value.Addr().Interface().(updateAssigner).assignFrom(a.value)
+	}
+	return nil
+}
+
+// fieldMaskPath converts a JSON pointer to a field of a patch struct to a
+// protobuf field mask path.
+func fieldMaskPath(pointer string) string {
+	keys := pointerKeys(pointer)
+	for i, key := range keys {
+		keys[i] = snakeCase(key)
+	}
+	return strings.Join(keys, ".")
+}
+
+// snakeCase converts a camelCase or PascalCase name, e.g. "postalCode" or
+// "UserID", to snake_case, e.g. "postal_code" or "user_id". Names that are
+// already snake_case are unchanged.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var builder strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Start a new word at a lowercase-to-uppercase transition, or at
+			// the last uppercase letter of an acronym followed by a
+			// lowercase letter, e.g. the "S" in "HTTPServer".
+			if i > 0 && runes[i-1] != '_' &&
+				(!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				builder.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
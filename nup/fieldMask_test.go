@@ -0,0 +1,243 @@
+package nup
+
+import (
+	"testing"
+
+	"github.com/nicheinc/expect"
+)
+
+type testMessageAddress struct {
+	City       string
+	PostalCode *string
+}
+
+type testMessage struct {
+	Name    string
+	Age     *int
+	Tags    []int
+	Address *testMessageAddress
+}
+
+type testMessagePatch struct {
+	Name    Update[string]          `json:"name"`
+	Age     Update[int]             `json:"age"`
+	Tags    SliceUpdate[int]        `json:"tags"`
+	Address testMessageAddressPatch `json:"address"`
+	Skipped Update[int]             `json:"-"`
+}
+
+type testMessageAddressPatch struct {
+	City       Update[string] `json:"city"`
+	PostalCode Update[string] `json:"postalCode"`
+}
+
+func TestFieldMaskPaths(t *testing.T) {
+	testCases := []struct {
+		name     string
+		patch    interface{}
+		expected []string
+	}{
+		{
+			name:     "Noop",
+			patch:    testMessagePatch{},
+			expected: nil,
+		},
+		{
+			name: "Changes",
+			patch: &testMessagePatch{
+				Name: Set("Alice"),
+				Tags: SliceRemove[int](),
+				Address: testMessageAddressPatch{
+					PostalCode: Set("12345"),
+				},
+				Skipped: Set(1),
+			},
+			expected: []string{"name", "tags", "address.postal_code"},
+		},
+		{
+			name: "UntaggedFields",
+			patch: struct {
+				UserID     Update[int]
+				HTTPServer Update[string]
+			}{
+				UserID:     Set(1),
+				HTTPServer: Remove[string](),
+			},
+			expected: []string{"user_id", "http_server"},
+		},
+		{
+			name:     "NotStruct",
+			patch:    5,
+			expected: nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual := FieldMaskPaths(testCase.patch)
+			expect.Equal(t, actual, testCase.expected)
+		})
+	}
+}
+
+func TestFromFieldMask(t *testing.T) {
+	var (
+		age        = 30
+		postalCode = "12345"
+		full       = testMessage{
+			Name: "Alice",
+			Age:  &age,
+			Address: &testMessageAddress{
+				PostalCode: &postalCode,
+			},
+		}
+	)
+	testCases := []struct {
+		name     string
+		paths    []string
+		full     interface{}
+		expected testMessagePatch
+	}{
+		{
+			name:     "Empty",
+			paths:    nil,
+			full:     full,
+			expected: testMessagePatch{},
+		},
+		{
+			name:  "SetAndRemove",
+			paths: []string{"name", "age", "tags", "address.postal_code"},
+			full:  &full,
+			expected: testMessagePatch{
+				Name: Set("Alice"),
+				Age:  Set(30),
+				Tags: SliceRemove[int](),
+				Address: testMessageAddressPatch{
+					PostalCode: Set("12345"),
+				},
+			},
+		},
+		{
+			name:  "NestedPatch",
+			paths: []string{"address"},
+			full:  full,
+			expected: testMessagePatch{
+				Address: testMessageAddressPatch{
+					City:       Remove[string](),
+					PostalCode: Set("12345"),
+				},
+			},
+		},
+		{
+			name:  "NilNestedStruct",
+			paths: []string{"address"},
+			full:  testMessage{},
+			expected: testMessagePatch{
+				Address: testMessageAddressPatch{
+					City:       Remove[string](),
+					PostalCode: Remove[string](),
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var actual testMessagePatch
+			err := FromFieldMask(testCase.paths, testCase.full, &actual)
+			expect.ErrorNil(t, err)
+			expect.Equal(t, actual, testCase.expected)
+		})
+	}
+}
+
+func TestFromFieldMask_RoundTrip(t *testing.T) {
+	patch := testMessagePatch{
+		Name: Set("Alice"),
+		Age:  Remove[int](),
+		Tags: SliceRemoveOrSet([]int{1, 2}),
+	}
+	var full testMessage
+	err := ApplyPatch(&full, patch)
+	expect.ErrorNil(t, err)
+
+	var actual testMessagePatch
+	err = FromFieldMask(FieldMaskPaths(patch), full, &actual)
+	expect.ErrorNil(t, err)
+	expect.Equal(t, actual, patch)
+}
+
+func TestFromFieldMask_Errors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		paths []string
+		full  interface{}
+		patch interface{}
+	}{
+		{
+			name:  "FullNotStruct",
+			paths: []string{"name"},
+			full:  5,
+			patch: &testMessagePatch{},
+		},
+		{
+			name:  "PatchNotPointer",
+			paths: []string{"name"},
+			full:  testMessage{},
+			patch: testMessagePatch{},
+		},
+		{
+			name:  "UnknownPath",
+			paths: []string{"name", "email"},
+			full:  testMessage{},
+			patch: &testMessagePatch{},
+		},
+		{
+			name:  "MissingField",
+			paths: []string{"name"},
+			full:  struct{ Age int }{},
+			patch: &testMessagePatch{},
+		},
+		{
+			name:  "MismatchedType",
+			paths: []string{"name", "age"},
+			full: struct {
+				Name string
+				Age  string
+			}{},
+			patch: &testMessagePatch{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := FromFieldMask(testCase.paths, testCase.full, testCase.patch)
+			expect.ErrorNonNil(t, err)
+			if patch, ok := testCase.patch.(*testMessagePatch); ok {
+				// The patch must be left untouched.
+				expect.Equal(t, *patch, testMessagePatch{})
+			}
+		})
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected string
+	}{
+		{name: "name", expected: "name"},
+		{name: "postal_code", expected: "postal_code"},
+		{name: "postalCode", expected: "postal_code"},
+		{name: "PostalCode", expected: "postal_code"},
+		{name: "UserID", expected: "user_id"},
+		{name: "HTTPServer", expected: "http_server"},
+		{name: "Line2Text", expected: "line2_text"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			expect.Equal(t, snakeCase(testCase.name), testCase.expected)
+		})
+	}
+}
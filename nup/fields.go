@@ -0,0 +1,297 @@
+package nup
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Field describes an Update or SliceUpdate field of a patch struct, as
+// reported by Fields.
+type Field struct {
+	// Path is the JSON pointer to the field, e.g. "/address/city".
+	Path string
+	// Name is the Go name of the field, qualified by the names of any
+	// enclosing patch struct fields, e.g. "Address.City".
+	Name string
+	// StructField is the reflected struct field, including its tags.
+	StructField reflect.StructField
+	// Operation is the operation the field's update performs.
+	Operation Operation
+	// Value is the value the field's update sets (a T for an Update[T] or a
+	// []T for a SliceUpdate[T]) if it's a set operation, or else nil.
+	Value interface{}
+}
+
+// Fields returns the Update and SliceUpdate fields of patch, which must be a
+// patch struct or a pointer to one, in declaration order. It recurses into
+// fields that are themselves patch structs, i.e. that contain Update or
+// SliceUpdate fields, and skips fields that encoding/json would skip. Fields
+// that are pointers to patch structs aren't supported, and are skipped too.
+// Fields returns nil if patch is not a struct or a non-nil pointer to one.
+//
+// Fields is intended for packages that inspect patch structs generically,
+// e.g. to validate them.
+func Fields(patch interface{}) []Field {
+	v, ok := structValue(patch)
+	if !ok {
+		return nil
+	}
+	var fields []Field
+	walkUpdates(v, "", "", func(field patchField) {
+		update := field.value.Interface().(updateMarshaller)
+		fields = append(fields, Field{
+			Path:        field.path,
+			Name:        field.name,
+			StructField: field.StructField,
+			Operation:   update.Operation(),
+			Value:       update.interfaceValue(),
+		})
+	})
+	return fields
+}
+
+// structValue returns the struct value of v, which may be a struct or a
+// non-nil pointer to one.
+func structValue(v interface{}) (reflect.Value, bool) {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+	return value, value.Kind() == reflect.Struct
+}
+
+// patchField is an update field of a patch struct, as visited by walkUpdates.
+type patchField struct {
+	structField
+	// value is the field's value, which is addressable if the patch struct
+	// is.
+	value reflect.Value
+	// path is the JSON pointer to the field.
+	path string
+	// name is the Go name of the field, qualified by the names of any
+	// enclosing patch struct fields.
+	name string
+}
+
+// walkUpdates calls visit for each update field of the patch struct v, located
+// at the JSON pointer path and the Go field name prefix, recursing into
+// nested patch structs.
+func walkUpdates(v reflect.Value, path, prefix string, visit func(patchField)) {
+	for _, field := range structFields(v.Type()) {
+		var (
+			fieldValue = v.FieldByIndex(field.Index)
+			fieldPath  = appendPointer(path, field.key)
+			fieldName  = prefix + field.Name
+		)
+		switch field.kind {
+		case updateKind:
+			visit(patchField{
+				structField: field,
+				value:       fieldValue,
+				path:        fieldPath,
+				name:        fieldName,
+			})
+		case patchKind:
+			walkUpdates(fieldValue, fieldPath, fieldName+".", visit)
+		}
+	}
+}
+
+// fieldKind classifies the fields of a patch struct.
+type fieldKind byte
+
+const (
+	// plainKind is a field of any type other than an update or patch struct.
+	plainKind fieldKind = iota
+	// updateKind is a field of type Update or SliceUpdate.
+	updateKind
+	// patchKind is a field whose type is itself a patch struct.
+	patchKind
+)
+
+// structField describes a field of a patch struct that encoding/json would
+// marshal.
+type structField struct {
+	reflect.StructField
+	// key is the field's JSON object key.
+	key string
+	// opts holds the options from the field's "nup" struct tag.
+	opts tagOptions
+	kind fieldKind
+}
+
+// tagOptions holds the options from a "nup" struct tag, e.g.
+// `nup:"required,nonnull"`.
+type tagOptions struct {
+	// nonnull rejects null, i.e. removal, when decoding the field.
+	nonnull bool
+	// required rejects input that omits the field when decoding it.
+	required bool
+	// immutable rejects input that includes the field when decoding it.
+	immutable bool
+	// roles maps role names to the permissions they grant on the field; see
+	// FieldSetForRole.
+	roles map[string]permission
+}
+
+// check returns ErrRequired, ErrImmutable, or ErrNull if the options forbid
+// decoding the given operation, or else nil.
+func (opts tagOptions) check(op Operation) error {
+	switch {
+	case op == OpNoop && opts.required:
+		return ErrRequired
+	case op != OpNoop && opts.immutable:
+		return ErrImmutable
+	case op == OpRemove && opts.nonnull:
+		return ErrNull
+	default:
+		return nil
+	}
+}
+
+func parseTagOptions(tag string) tagOptions {
+	var opts tagOptions
+	for _, opt := range strings.Split(tag, ",") {
+		switch opt {
+		case "nonnull":
+			opts.nonnull = true
+		case "required":
+			opts.required = true
+		case "immutable":
+			opts.immutable = true
+		default:
+			if role, ok := strings.CutPrefix(opt, "role="); ok {
+				name, perm := parseRole(role)
+				if opts.roles == nil {
+					opts.roles = map[string]permission{}
+				}
+				opts.roles[name] |= perm
+			}
+		}
+	}
+	return opts
+}
+
+var updateMarshallerType = reflect.TypeOf((*updateMarshaller)(nil)).Elem()
+
+// isUpdateType returns whether t is an Update or SliceUpdate type.
+func isUpdateType(t reflect.Type) bool {
+	return t.Implements(updateMarshallerType)
+}
+
+// checkPatchType returns an error if the patch struct type t, or a patch struct
+// nested in it, has a field that is a pointer to a patch struct. Such fields
+// aren't supported, since a nil pointer can't hold updates; rather than being
+// ignored, they're rejected by the functions that return errors.
+func checkPatchType(t reflect.Type) error {
+	for _, field := range structFields(t) {
+		switch {
+		case field.kind == patchKind:
+			if err := checkPatchType(field.Type); err != nil {
+				return err
+			}
+		case field.kind == plainKind && field.Type.Kind() == reflect.Pointer && isPatchType(field.Type.Elem()):
+			return fmt.Errorf("nup: field %s of %s is a pointer to a patch struct, which is not supported; use a %s field instead", field.Name, t, field.Type.Elem())
+		}
+	}
+	return nil
+}
+
+// isPatchType returns whether t is a patch struct type, i.e. a struct with at
+// least one field that is an update or (recursively) a patch struct.
+func isPatchType(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || isUpdateType(t) {
+		return false
+	}
+	for _, field := range structFields(t) {
+		if field.kind != plainKind {
+			return true
+		}
+	}
+	return false
+}
+
+// structFields returns the fields of the struct type t that encoding/json
+// would marshal, in declaration order. Like MarshalJSON, it skips anonymous
+// fields.
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		// Skip anonymous and unexported fields.
+		if field.Anonymous || !field.IsExported() {
+			continue
+		}
+		key, ok := jsonKey(field)
+		if !ok {
+			continue
+		}
+		kind := plainKind
+		switch {
+		case isUpdateType(field.Type):
+			kind = updateKind
+		case isPatchType(field.Type):
+			kind = patchKind
+		}
+		fields = append(fields, structField{
+			StructField: field,
+			key:         key,
+			opts:        parseTagOptions(field.Tag.Get("nup")),
+			kind:        kind,
+		})
+	}
+	return fields
+}
+
+// jsonKey returns the JSON object key for a struct field, or false if
+// encoding/json would skip the field.
+func jsonKey(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		return field.Name, true
+	}
+	return name, true
+}
+
+// lookupField returns the field with the given JSON key, preferring an exact
+// match but otherwise accepting a case-insensitive match, like encoding/json.
+func lookupField(fields []structField, key string) (structField, bool) {
+	for _, field := range fields {
+		if field.key == key {
+			return field, true
+		}
+	}
+	for _, field := range fields {
+		if strings.EqualFold(field.key, key) {
+			return field, true
+		}
+	}
+	return structField{}, false
+}
+
+// pointerEscaper escapes JSON object keys for use in JSON pointers, per RFC
+// 6901.
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// appendPointer returns the JSON pointer to the key within the object at path.
+func appendPointer(path, key string) string {
+	return path + "/" + pointerEscaper.Replace(key)
+}
+
+// pointerUnescaper reverses pointerEscaper.
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// pointerKeys returns the unescaped object keys that make up the JSON pointer
+// path, e.g. ["address", "city"] for "/address/city".
+func pointerKeys(path string) []string {
+	keys := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, key := range keys {
+		keys[i] = pointerUnescaper.Replace(key)
+	}
+	return keys
+}
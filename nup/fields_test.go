@@ -0,0 +1,114 @@
+package nup
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nicheinc/expect"
+)
+
+func TestFields(t *testing.T) {
+	patch := testPatch{
+		Name:    Set("Alice"),
+		Age:     Remove[int](),
+		Address: testAddressPatch{City: Set("Paris")},
+	}
+	type field struct {
+		Path      string
+		Name      string
+		Operation Operation
+		Value     interface{}
+	}
+	var actual []field
+	for _, f := range Fields(&patch) {
+		actual = append(actual, field{
+			Path:      f.Path,
+			Name:      f.Name,
+			Operation: f.Operation,
+			Value:     f.Value,
+		})
+	}
+	expected := []field{
+		{Path: "/id", Name: "ID", Operation: OpNoop},
+		{Path: "/name", Name: "Name", Operation: OpSet, Value: "Alice"},
+		{Path: "/age", Name: "Age", Operation: OpRemove},
+		{Path: "/tags", Name: "Tags", Operation: OpNoop},
+		{Path: "/address/city", Name: "Address.City", Operation: OpSet, Value: "Paris"},
+		{Path: "/address/zip", Name: "Address.Zip", Operation: OpNoop},
+	}
+	expect.Equal(t, actual, expected)
+}
+
+func TestFields_EscapedPath(t *testing.T) {
+	patch := struct {
+		Ratio Update[int] `json:"a/b~c"`
+	}{}
+	fields := Fields(patch)
+	expect.Equal(t, len(fields), 1)
+	expect.Equal(t, fields[0].Path, "/a~1b~0c")
+}
+
+func TestFields_NotStruct(t *testing.T) {
+	expect.Equal(t, len(Fields(5)), 0)
+	expect.Equal(t, len(Fields((*testPatch)(nil))), 0)
+}
+
+// testPointerPatch holds a nested patch struct by pointer, which isn't
+// supported.
+type testPointerPatch struct {
+	Name    Update[string]    `json:"name"`
+	Address *testAddressPatch `json:"address"`
+}
+
+func TestCheckPatchType(t *testing.T) {
+	type recursivePatch struct {
+		Name Update[string]
+		Next *recursivePatch
+	}
+	testCases := []struct {
+		name       string
+		patch      interface{}
+		errorCheck expect.ErrorCheck
+	}{
+		{
+			name:       "Nested",
+			patch:      testPatch{},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "Pointer",
+			patch:      testPointerPatch{},
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name: "NestedPointer",
+			patch: struct {
+				Outer struct {
+					Age     Update[int]
+					Address *testAddressPatch
+				}
+			}{},
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name:       "Recursive",
+			patch:      recursivePatch{},
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name: "PointerToPlainStruct",
+			patch: struct {
+				Name    Update[string]
+				Address *testAddress
+			}{},
+			errorCheck: expect.ErrorNil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := checkPatchType(reflect.TypeOf(testCase.patch))
+			testCase.errorCheck(t, err)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package nup
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// flagger is implemented by Update and SliceUpdate pointers, so that
+// RegisterFlags can create flags for them.
+type flagger interface {
+	flagValue() flag.Value
+	clearFlagValue() flag.Value
+}
+
+// Flag returns a flag.Value that sets u. If the flag isn't given, u is left
+// unchanged, e.g. as a no-op. If it's given an empty value, e.g. "-name=", u
+// becomes a removal; otherwise, u is set to the value, which is parsed like
+// UnmarshalText parses it: with T's own UnmarshalText method, if it has one,
+// or else strconv. For example:
+//
+//	var name nup.Update[string]
+//	flag.Var(nup.Flag(&name), "name", "set the name")
+//
+// See also ClearFlag, which provides an explicit flag for removal.
+func Flag[T comparable](u *Update[T]) flag.Value {
+	return &updateFlag[T]{u: u}
+}
+
+type updateFlag[T comparable] struct {
+	u *Update[T]
+}
+
+func (f *updateFlag[T]) String() string {
+	// The flag package calls String on a zero-valued flag.Value.
+	if f.u == nil || f.u.op != OpSet {
+		return ""
+	}
+	text, err := formatText(f.u.value)
+	if err != nil {
+		return fmt.Sprint(f.u.value)
+	}
+	return string(text)
+}
+
+func (f *updateFlag[T]) Set(s string) error {
+	if s == "" {
+		*f.u = Remove[T]()
+		return nil
+	}
+	var value T
+	if err := parseText([]byte(s), &value); err != nil {
+		return err
+	}
+	*f.u = Set(value)
+	return nil
+}
+
+// SliceFlag returns a flag.Value that sets u. If the flag isn't given, u is
+// left unchanged, e.g. as a no-op. Each time it's given a non-empty value, the
+// value is parsed like SliceUpdate.UnmarshalText parses each element and
+// appended to u's value, so that, e.g., "-tag=a -tag=b" sets u to [a, b]. If
+// it's given an empty value, e.g. "-tag=", u becomes a removal.
+//
+// See also SliceClearFlag, which provides an explicit flag for removal.
+func SliceFlag[T comparable](u *SliceUpdate[T]) flag.Value {
+	return &sliceUpdateFlag[T]{u: u}
+}
+
+type sliceUpdateFlag[T comparable] struct {
+	u *SliceUpdate[T]
+}
+
+func (f *sliceUpdateFlag[T]) String() string {
+	if f.u == nil || f.u.op != OpSet {
+		return ""
+	}
+	elements := make([]string, len(f.u.value))
+	for i, element := range f.u.value {
+		text, err := formatText(element)
+		if err != nil {
+			elements[i] = fmt.Sprint(element)
+		} else {
+			elements[i] = string(text)
+		}
+	}
+	return strings.Join(elements, ",")
+}
+
+func (f *sliceUpdateFlag[T]) Set(s string) error {
+	if s == "" {
+		*f.u = SliceRemove[T]()
+		return nil
+	}
+	var element T
+	if err := parseText([]byte(s), &element); err != nil {
+		return err
+	}
+	if f.u.op != OpSet {
+		*f.u = SliceRemoveOrSet([]T{})
+	}
+	f.u.value = append(f.u.value, element)
+	return nil
+}
+
+// ClearFlag returns a boolean flag.Value that makes u a removal if it's given,
+// e.g. "-name-clear". It's intended to accompany a flag created with Flag, for
+// users who find an empty value too subtle. If both are given, the last one
+// wins.
+func ClearFlag[T comparable](u *Update[T]) flag.Value {
+	return &clearFlag{
+		isRemove: func() bool { return u.IsRemove() },
+		remove:   func() { *u = Remove[T]() },
+		reset:    func() { *u = Noop[T]() },
+	}
+}
+
+// SliceClearFlag returns a boolean flag.Value that makes u a removal if it's
+// given, e.g. "-tag-clear". It's intended to accompany a flag created with
+// SliceFlag.
+func SliceClearFlag[T comparable](u *SliceUpdate[T]) flag.Value {
+	return &clearFlag{
+		isRemove: func() bool { return u.IsRemove() },
+		remove:   func() { *u = SliceRemove[T]() },
+		reset:    func() { *u = SliceNoop[T]() },
+	}
+}
+
+type clearFlag struct {
+	isRemove func() bool
+	remove   func()
+	reset    func()
+}
+
+func (f *clearFlag) String() string {
+	return strconv.FormatBool(f.isRemove != nil && f.isRemove())
+}
+
+func (f *clearFlag) Set(s string) error {
+	value, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	switch {
+	case value:
+		f.remove()
+	case f.isRemove():
+		// An explicit false undoes an earlier removal.
+		f.reset()
+	}
+	return nil
+}
+
+// IsBoolFlag allows the flag to be given without a value, e.g. "-name-clear".
+func (f *clearFlag) IsBoolFlag() bool {
+	return true
+}
+
+// RegisterFlags registers two flags with fs for each Update and SliceUpdate
+// field of the patch struct pointed to by patch: a flag created by Flag or
+// SliceFlag, named by the field's JSON key, and a flag created by ClearFlag or
+// SliceClearFlag, named by the key followed by "-clear". The fields of nested
+// patch structs are named by their keys joined with dots, e.g. "address.city"
+// and "address.city-clear".
+func RegisterFlags(fs *flag.FlagSet, patch interface{}) error {
+	v := reflect.ValueOf(patch)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("nup: RegisterFlags requires a non-nil pointer to a struct, not %T", patch)
+	}
+	for _, field := range patchFields(patch) {
+		var (
+			name = valuesKey(field.path)
+			f    = field.value.Addr().Interface().(flagger)
+		)
+		fs.Var(f.flagValue(), name, fmt.Sprintf("set %s (an empty value removes it)", name))
+		fs.Var(f.clearFlagValue(), name+"-clear", fmt.Sprintf("remove %s", name))
+	}
+	return nil
+}
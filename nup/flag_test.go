@@ -0,0 +1,190 @@
+package nup
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/nicheinc/expect"
+)
+
+func TestFlag(t *testing.T) {
+	testCases := []struct {
+		name       string
+		args       []string
+		expected   Update[int]
+		errorCheck expect.ErrorCheck
+	}{
+		{
+			name:       "Absent",
+			args:       nil,
+			expected:   Noop[int](),
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "Empty",
+			args:       []string{"-age="},
+			expected:   Remove[int](),
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "Set",
+			args:       []string{"-age=0"},
+			expected:   Set(0),
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "Clear",
+			args:       []string{"-age=1", "-age-clear"},
+			expected:   Remove[int](),
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "SetAfterClear",
+			args:       []string{"-age-clear", "-age", "1"},
+			expected:   Set(1),
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "ClearFalse",
+			args:       []string{"-age-clear", "-age-clear=false"},
+			expected:   Noop[int](),
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "Invalid",
+			args:       []string{"-age=old"},
+			errorCheck: expect.ErrorNonNil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var (
+				actual Update[int]
+				fs     = flag.NewFlagSet("test", flag.ContinueOnError)
+			)
+			fs.SetOutput(io.Discard)
+			fs.Var(Flag(&actual), "age", "")
+			fs.Var(ClearFlag(&actual), "age-clear", "")
+			err := fs.Parse(testCase.args)
+			testCase.errorCheck(t, err)
+			if err == nil {
+				expect.Equal(t, actual, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestFlag_TextUnmarshaler(t *testing.T) {
+	var (
+		actual Update[netip.Addr]
+		fs     = flag.NewFlagSet("test", flag.ContinueOnError)
+	)
+	fs.Var(Flag(&actual), "addr", "")
+	err := fs.Parse([]string{"-addr=192.0.2.1"})
+	expect.ErrorNil(t, err)
+	expect.Equal(t, actual, Set(netip.MustParseAddr("192.0.2.1")))
+	expect.Equal(t, fs.Lookup("addr").Value.String(), "192.0.2.1")
+}
+
+func TestSliceFlag(t *testing.T) {
+	testCases := []struct {
+		name       string
+		args       []string
+		expected   SliceUpdate[string]
+		errorCheck expect.ErrorCheck
+	}{
+		{
+			name:       "Absent",
+			args:       nil,
+			expected:   SliceNoop[string](),
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "Empty",
+			args:       []string{"-tag="},
+			expected:   SliceRemove[string](),
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "Repeated",
+			args:       []string{"-tag=a", "-tag", "b"},
+			expected:   SliceRemoveOrSet([]string{"a", "b"}),
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "SetAfterEmpty",
+			args:       []string{"-tag=", "-tag=a"},
+			expected:   SliceRemoveOrSet([]string{"a"}),
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "Clear",
+			args:       []string{"-tag=a", "-tag-clear"},
+			expected:   SliceRemove[string](),
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "InvalidClear",
+			args:       []string{"-tag-clear=maybe"},
+			errorCheck: expect.ErrorNonNil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var (
+				actual SliceUpdate[string]
+				fs     = flag.NewFlagSet("test", flag.ContinueOnError)
+			)
+			fs.SetOutput(io.Discard)
+			fs.Var(SliceFlag(&actual), "tag", "")
+			fs.Var(SliceClearFlag(&actual), "tag-clear", "")
+			err := fs.Parse(testCase.args)
+			testCase.errorCheck(t, err)
+			if err == nil {
+				expect.Equal(t, actual, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestRegisterFlags(t *testing.T) {
+	var (
+		patch testFormPatch
+		fs    = flag.NewFlagSet("test", flag.ContinueOnError)
+	)
+	err := RegisterFlags(fs, &patch)
+	expect.ErrorNil(t, err)
+	err = fs.Parse([]string{
+		"-name=Alice",
+		"-age-clear",
+		"-tags=a", "-tags=b",
+		"-address.city=Paris",
+		"-address.zip=",
+	})
+	expect.ErrorNil(t, err)
+	expect.Equal(t, patch, testFormPatch{
+		Name:    Set("Alice"),
+		Age:     Remove[int](),
+		Tags:    SliceRemoveOrSet([]string{"a", "b"}),
+		Address: testAddressPatch{City: Set("Paris"), Zip: Remove[string]()},
+	})
+
+	// Defaults, which are all no-ops, aren't printed.
+	var usage bytes.Buffer
+	fs.SetOutput(&usage)
+	fs.PrintDefaults()
+	expect.Equal(t, strings.Contains(usage.String(), "-address.city-clear"), true)
+	expect.Equal(t, strings.Contains(usage.String(), "default"), false)
+}
+
+func TestRegisterFlags_NotPointer(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	err := RegisterFlags(fs, testFormPatch{})
+	expect.ErrorNonNil(t, err)
+}
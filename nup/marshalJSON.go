@@ -91,6 +91,8 @@ func MarshalJSON(v interface{}) ([]byte, error) {
 }
 
 type updateMarshaller interface {
+	// Operation returns the operation the update performs.
+	Operation() Operation
 	// IsChange utilizes the IsChange methods on Update and SliceUpdate to
 	// detect whether the update should be marshalled to JSON.
 	IsChange() bool
@@ -0,0 +1,215 @@
+package nup
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// ErrForbidden indicates a change to a field that the caller isn't allowed to
+// make.
+var ErrForbidden = errors.New("not allowed")
+
+// permission is a set of operations allowed on a field.
+type permission byte
+
+const (
+	permSet permission = 1 << iota
+	permRemove
+	permAll = permSet | permRemove
+)
+
+// allows returns whether p permits the given operation. Every permission,
+// including none, permits a no-op.
+func (p permission) allows(op Operation) bool {
+	switch op {
+	case OpSet:
+		return p&permSet != 0
+	case OpRemove:
+		return p&permRemove != 0
+	default:
+		return true
+	}
+}
+
+// parseRole parses the value of a "role=" option in a "nup" struct tag, e.g.
+// "admin" or "user:set".
+func parseRole(role string) (string, permission) {
+	name, op, ok := strings.Cut(role, ":")
+	if !ok {
+		return name, permAll
+	}
+	switch op {
+	case "set":
+		return name, permSet
+	case "remove":
+		return name, permRemove
+	default:
+		return name, 0
+	}
+}
+
+// FieldSet is a set of fields of a patch struct that a caller may change,
+// distinguishing between permission to set a field and permission to remove
+// it. Fields are identified by their JSON keys, e.g. "avatar", or by JSON
+// pointers for fields of nested patch structs, e.g. "address/city". A nested
+// patch struct's key or pointer, e.g. "address", identifies all of its fields.
+//
+// The zero FieldSet allows no changes. FieldSet methods return modified copies,
+// leaving the original unchanged, so a FieldSet can be safely shared.
+type FieldSet struct {
+	perms map[string]permission
+}
+
+// NewFieldSet returns a FieldSet that allows setting and removing the given
+// fields.
+func NewFieldSet(fields ...string) FieldSet {
+	return FieldSet{}.Allow(fields...)
+}
+
+// Allow returns a copy of s that also allows setting and removing the given
+// fields.
+func (s FieldSet) Allow(fields ...string) FieldSet {
+	return s.with(permAll, fields)
+}
+
+// AllowSet returns a copy of s that also allows setting the given fields.
+func (s FieldSet) AllowSet(fields ...string) FieldSet {
+	return s.with(permSet, fields)
+}
+
+// AllowRemove returns a copy of s that also allows removing the given fields.
+func (s FieldSet) AllowRemove(fields ...string) FieldSet {
+	return s.with(permRemove, fields)
+}
+
+func (s FieldSet) with(perm permission, fields []string) FieldSet {
+	perms := make(map[string]permission, len(s.perms)+len(fields))
+	for path, p := range s.perms {
+		perms[path] = p
+	}
+	for _, field := range fields {
+		if !strings.HasPrefix(field, "/") {
+			field = "/" + field
+		}
+		perms[field] |= perm
+	}
+	return FieldSet{perms: perms}
+}
+
+// Allows returns whether s allows the given operation on the field at the
+// given JSON pointer, e.g. "/address/city". Every FieldSet allows a no-op.
+func (s FieldSet) Allows(path string, op Operation) bool {
+	return s.permission(path).allows(op)
+}
+
+// permission returns the permissions s grants on the field at path, including
+// those granted on enclosing patch structs.
+func (s FieldSet) permission(path string) permission {
+	var perm permission
+	for {
+		perm |= s.perms[path]
+		i := strings.LastIndex(path, "/")
+		if i <= 0 {
+			return perm
+		}
+		path = path[:i]
+	}
+}
+
+// FieldSetForRole returns the FieldSet of fields of patch, a patch struct or a
+// pointer to one, that the given role may change, as declared by "role="
+// options in the fields' "nup" struct tags. For example:
+//
+//	type UserPatch struct {
+//		Name   nup.Update[string] `json:"name" nup:"role=user,role=admin"`
+//		Avatar nup.Update[string] `json:"avatar" nup:"role=user:set,role=admin"`
+//		Plan   nup.Update[string] `json:"plan" nup:"role=admin"`
+//	}
+//
+// A "role=name" option allows the role to set and remove the field, while
+// "role=name:set" and "role=name:remove" allow only setting or only removing
+// it. Above, a user may set but not remove their avatar, and may not change
+// their plan at all.
+func FieldSetForRole(patch interface{}, role string) FieldSet {
+	var set FieldSet
+	for _, field := range patchFields(patch) {
+		if perm := field.opts.roles[role]; perm != 0 {
+			set = set.with(perm, []string{field.path})
+		}
+	}
+	return set
+}
+
+// Mask checks that allowed permits every change made by patch, a patch struct
+// or a pointer to one. It returns the disallowed changes, if any, as a
+// FieldErrors wrapping ErrForbidden. It returns an error that is not a
+// FieldErrors if a field of patch is a pointer to a patch struct, which isn't
+// supported.
+func Mask(patch interface{}, allowed FieldSet) error {
+	if v, ok := structValue(patch); ok {
+		if err := checkPatchType(v.Type()); err != nil {
+			return err
+		}
+	}
+	var errs FieldErrors
+	for _, field := range patchFields(patch) {
+		op := field.value.Interface().(updateMarshaller).Operation()
+		if !allowed.Allows(field.path, op) {
+			errs = append(errs, &FieldError{
+				Path:  field.path,
+				Field: field.name,
+				Op:    op,
+				Err:   ErrForbidden,
+			})
+		}
+	}
+	return errs.Err()
+}
+
+// Sanitize returns a copy of patch, a patch struct or a pointer to one, in
+// which each change that allowed doesn't permit is replaced with a no-op. If
+// patch is a pointer, Sanitize returns a pointer to a new struct; patch itself
+// is never modified.
+func Sanitize[P any](patch P, allowed FieldSet) P {
+	value := reflect.ValueOf(&patch).Elem()
+	if value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	var result reflect.Value
+	switch {
+	case value.Kind() == reflect.Struct:
+		result = reflect.New(value.Type()).Elem()
+		result.Set(value)
+	case value.Kind() == reflect.Pointer && !value.IsNil() && value.Elem().Kind() == reflect.Struct:
+		result = reflect.New(value.Type().Elem())
+		result.Elem().Set(value.Elem())
+	default:
+		return patch
+	}
+	target := result
+	if target.Kind() == reflect.Pointer {
+		target = target.Elem()
+	}
+	walkUpdates(target, "", "", func(field patchField) {
+		op := field.value.Interface().(updateMarshaller).Operation()
+		if !allowed.Allows(field.path, op) {
+			field.value.SetZero()
+		}
+	})
+	return result.Interface().(P)
+}
+
+// patchFields returns the update fields of patch, a patch struct or a pointer
+// to one, or nil if patch is neither.
+func patchFields(patch interface{}) []patchField {
+	v, ok := structValue(patch)
+	if !ok {
+		return nil
+	}
+	var fields []patchField
+	walkUpdates(v, "", "", func(field patchField) {
+		fields = append(fields, field)
+	})
+	return fields
+}
@@ -0,0 +1,156 @@
+package nup
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/nicheinc/expect"
+)
+
+type testProfilePatch struct {
+	Name    Update[string]   `json:"name" nup:"role=user,role=admin"`
+	Avatar  Update[string]   `json:"avatar" nup:"role=user:set,role=admin"`
+	Plan    Update[string]   `json:"plan" nup:"role=admin"`
+	Address testAddressPatch `json:"address"`
+}
+
+func TestFieldSet_Allows(t *testing.T) {
+	set := NewFieldSet("name", "/address").
+		AllowSet("avatar").
+		AllowRemove("plan")
+	testCases := []struct {
+		name     string
+		path     string
+		op       Operation
+		expected bool
+	}{
+		{name: "Noop", path: "/unknown", op: OpNoop, expected: true},
+		{name: "Set", path: "/name", op: OpSet, expected: true},
+		{name: "Remove", path: "/name", op: OpRemove, expected: true},
+		{name: "SetOnly", path: "/avatar", op: OpSet, expected: true},
+		{name: "SetOnlyRemove", path: "/avatar", op: OpRemove, expected: false},
+		{name: "RemoveOnly", path: "/plan", op: OpRemove, expected: true},
+		{name: "RemoveOnlySet", path: "/plan", op: OpSet, expected: false},
+		{name: "Nested", path: "/address/city", op: OpSet, expected: true},
+		{name: "Unknown", path: "/unknown", op: OpSet, expected: false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual := set.Allows(testCase.path, testCase.op)
+			expect.Equal(t, actual, testCase.expected)
+		})
+	}
+}
+
+func TestFieldSet_Immutable(t *testing.T) {
+	set := NewFieldSet("name")
+	_ = set.Allow("plan")
+	expect.Equal(t, set.Allows("/plan", OpSet), false)
+	expect.Equal(t, FieldSet{}.Allows("/name", OpSet), false)
+}
+
+func TestFieldSetForRole(t *testing.T) {
+	user := FieldSetForRole(testProfilePatch{}, "user")
+	expect.Equal(t, user, FieldSet{}.Allow("name").AllowSet("avatar"), cmp.AllowUnexported(FieldSet{}))
+	admin := FieldSetForRole(&testProfilePatch{}, "admin")
+	expect.Equal(t, admin, NewFieldSet("name", "avatar", "plan"), cmp.AllowUnexported(FieldSet{}))
+	none := FieldSetForRole(testProfilePatch{}, "guest")
+	expect.Equal(t, none, FieldSet{}, cmp.AllowUnexported(FieldSet{}))
+}
+
+func TestMask(t *testing.T) {
+	user := FieldSetForRole(testProfilePatch{}, "user")
+	testCases := []struct {
+		name     string
+		patch    testProfilePatch
+		expected []FieldError
+	}{
+		{
+			name:  "Noop",
+			patch: testProfilePatch{},
+		},
+		{
+			name: "Allowed",
+			patch: testProfilePatch{
+				Name:   Remove[string](),
+				Avatar: Set("cat.png"),
+			},
+		},
+		{
+			name: "Forbidden",
+			patch: testProfilePatch{
+				Name:    Set("Alice"),
+				Avatar:  Remove[string](),
+				Plan:    Set("pro"),
+				Address: testAddressPatch{City: Set("Paris")},
+			},
+			expected: []FieldError{
+				{Path: "/avatar", Field: "Avatar", Op: OpRemove, Err: ErrForbidden},
+				{Path: "/plan", Field: "Plan", Op: OpSet, Err: ErrForbidden},
+				{Path: "/address/city", Field: "Address.City", Op: OpSet, Err: ErrForbidden},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := Mask(testCase.patch, user)
+			if testCase.expected == nil {
+				expect.ErrorNil(t, err)
+				return
+			}
+			var fieldErrors FieldErrors
+			if !errors.As(err, &fieldErrors) {
+				t.Fatalf("Expected FieldErrors, got %T", err)
+			}
+			actual := make([]FieldError, len(fieldErrors))
+			for i, fieldError := range fieldErrors {
+				actual[i] = *fieldError
+			}
+			expect.Equal(t, actual, testCase.expected, cmpopts.EquateErrors())
+		})
+	}
+}
+
+func TestMask_PointerToPatch(t *testing.T) {
+	err := Mask(testPointerPatch{}, NewFieldSet("/name"))
+	expect.ErrorNonNil(t, err)
+	var errs FieldErrors
+	expect.Equal(t, errors.As(err, &errs), false)
+}
+
+func TestSanitize(t *testing.T) {
+	user := FieldSetForRole(testProfilePatch{}, "user")
+	patch := testProfilePatch{
+		Name:    Set("Alice"),
+		Avatar:  Remove[string](),
+		Plan:    Set("pro"),
+		Address: testAddressPatch{City: Set("Paris")},
+	}
+	original := patch
+	expected := testProfilePatch{
+		Name: Set("Alice"),
+	}
+
+	t.Run("Struct", func(t *testing.T) {
+		actual := Sanitize(patch, user)
+		expect.Equal(t, actual, expected)
+		expect.Equal(t, patch, original)
+	})
+	t.Run("Pointer", func(t *testing.T) {
+		actual := Sanitize(&patch, user)
+		expect.Equal(t, *actual, expected)
+		expect.Equal(t, patch, original)
+	})
+	t.Run("Interface", func(t *testing.T) {
+		actual := Sanitize[interface{}](patch, user)
+		expect.Equal(t, actual, interface{}(expected))
+	})
+	t.Run("NilPointer", func(t *testing.T) {
+		actual := Sanitize((*testProfilePatch)(nil), user)
+		expect.Equal(t, actual, nil)
+	})
+}
@@ -1,5 +1,11 @@
 package nup
 
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
 // Operation represents the operation that an update performs. The OpNoop,
 // OpRemove, and OpSet constants are the only valid values of this type.
 type Operation byte
@@ -13,13 +19,91 @@ const (
 	OpSet
 )
 
+// ParseOperation returns the operation with the given name, as returned by
+// String. It also accepts "noop" as an alias for "no-op".
+func ParseOperation(name string) (Operation, error) {
+	switch name {
+	case "no-op", "noop":
+		return OpNoop, nil
+	case "remove":
+		return OpRemove, nil
+	case "set":
+		return OpSet, nil
+	default:
+		return 0, fmt.Errorf("nup: unknown operation %q", name)
+	}
+}
+
+// Valid returns whether o is one of the OpNoop, OpRemove, and OpSet constants.
+func (o Operation) Valid() bool {
+	return o <= OpSet
+}
+
+// String implements fmt.Stringer. It returns "no-op", "remove", or "set" for
+// valid operations, or else "Operation(N)", where N is the numeric value.
 func (o Operation) String() string {
 	switch o {
 	case OpNoop:
 		return "no-op"
 	case OpRemove:
 		return "remove"
-	default: // Set
+	case OpSet:
 		return "set"
+	default:
+		return "Operation(" + strconv.Itoa(int(o)) + ")"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler, returning the operation's
+// name, as returned by String. It returns an error if o is not valid.
+func (o Operation) MarshalText() ([]byte, error) {
+	if !o.Valid() {
+		return nil, fmt.Errorf("nup: cannot marshal invalid %s", o)
+	}
+	return []byte(o.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing the operation's
+// name with ParseOperation.
+func (o *Operation) UnmarshalText(text []byte) error {
+	op, err := ParseOperation(string(text))
+	if err != nil {
+		return err
+	}
+	*o = op
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, marshalling the operation as a JSON
+// string holding its name, as returned by String. It returns an error if o is
+// not valid.
+//
+// Note that earlier versions marshalled an Operation as a number. UnmarshalJSON
+// still accepts those numbers. The gob encoding is unaffected, remaining
+// numeric.
+func (o Operation) MarshalJSON() ([]byte, error) {
+	text, err := o.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, parsing a JSON string holding the
+// operation's name with ParseOperation. For compatibility with data marshalled
+// by earlier versions, it also accepts the number of a valid operation.
+func (o *Operation) UnmarshalJSON(data []byte) error {
+	var number uint8
+	if err := json.Unmarshal(data, &number); err == nil {
+		if op := Operation(number); op.Valid() {
+			*o = op
+			return nil
+		}
+		return fmt.Errorf("nup: unknown operation %d", number)
+	}
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
 	}
+	return o.UnmarshalText([]byte(name))
 }
@@ -1,6 +1,10 @@
 package nup
 
 import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
 	"testing"
 
 	"github.com/nicheinc/expect"
@@ -27,6 +31,11 @@ func TestOperation_String(t *testing.T) {
 			op:       OpSet,
 			expected: "set",
 		},
+		{
+			name:     "Invalid",
+			op:       Operation(7),
+			expected: "Operation(7)",
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -36,3 +45,199 @@ func TestOperation_String(t *testing.T) {
 		})
 	}
 }
+
+func TestParseOperation(t *testing.T) {
+	testCases := []struct {
+		name       string
+		expected   Operation
+		errorCheck expect.ErrorCheck
+	}{
+		{
+			name:       "no-op",
+			expected:   OpNoop,
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "noop",
+			expected:   OpNoop,
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "remove",
+			expected:   OpRemove,
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "set",
+			expected:   OpSet,
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "Set",
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name:       "",
+			errorCheck: expect.ErrorNonNil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, err := ParseOperation(testCase.name)
+			testCase.errorCheck(t, err)
+			expect.Equal(t, actual, testCase.expected)
+		})
+	}
+}
+
+// Ensure implementation of the encoding interfaces.
+var (
+	_ encoding.TextMarshaler   = OpNoop
+	_ encoding.TextUnmarshaler = new(Operation)
+	_ json.Marshaler           = OpNoop
+	_ json.Unmarshaler         = new(Operation)
+)
+
+func TestOperation_Valid(t *testing.T) {
+	expect.Equal(t, OpNoop.Valid(), true)
+	expect.Equal(t, OpRemove.Valid(), true)
+	expect.Equal(t, OpSet.Valid(), true)
+	expect.Equal(t, Operation(3).Valid(), false)
+	expect.Equal(t, Operation(255).Valid(), false)
+}
+
+func TestOperation_MarshalJSON(t *testing.T) {
+	testCases := []struct {
+		name       string
+		op         Operation
+		expected   string
+		errorCheck expect.ErrorCheck
+	}{
+		{
+			name:       "Noop",
+			op:         OpNoop,
+			expected:   `"no-op"`,
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "Remove",
+			op:         OpRemove,
+			expected:   `"remove"`,
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "Set",
+			op:         OpSet,
+			expected:   `"set"`,
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "Invalid",
+			op:         Operation(3),
+			errorCheck: expect.ErrorNonNil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, err := json.Marshal(testCase.op)
+			testCase.errorCheck(t, err)
+			if err != nil {
+				return
+			}
+			expect.Equal(t, string(actual), testCase.expected)
+
+			var roundTrip Operation
+			err = json.Unmarshal(actual, &roundTrip)
+			expect.ErrorNil(t, err)
+			expect.Equal(t, roundTrip, testCase.op)
+		})
+	}
+}
+
+func TestOperation_UnmarshalJSON(t *testing.T) {
+	testCases := []struct {
+		name       string
+		json       string
+		expected   Operation
+		errorCheck expect.ErrorCheck
+	}{
+		{
+			name:       "Alias",
+			json:       `"noop"`,
+			expected:   OpNoop,
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "Unknown",
+			json:       `"replace"`,
+			expected:   OpSet,
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name:       "LegacyNumber",
+			json:       `1`,
+			expected:   OpRemove,
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "InvalidNumber",
+			json:       `3`,
+			expected:   OpSet,
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name:       "NegativeNumber",
+			json:       `-1`,
+			expected:   OpSet,
+			errorCheck: expect.ErrorNonNil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// Start from OpSet to check that errors leave the operation
+			// unchanged.
+			actual := OpSet
+			err := json.Unmarshal([]byte(testCase.json), &actual)
+			testCase.errorCheck(t, err)
+			expect.Equal(t, actual, testCase.expected)
+		})
+	}
+}
+
+func TestOperation_TextMapKey(t *testing.T) {
+	counts := map[Operation]int{OpRemove: 1, OpSet: 2}
+	data, err := json.Marshal(counts)
+	expect.ErrorNil(t, err)
+	expect.Equal(t, string(data), `{"remove":1,"set":2}`)
+
+	var actual map[Operation]int
+	err = json.Unmarshal(data, &actual)
+	expect.ErrorNil(t, err)
+	expect.Equal(t, actual, counts)
+}
+
+func TestOperation_Gob(t *testing.T) {
+	// The gob encoding of an Operation remains numeric, compatible with data
+	// encoded by earlier versions.
+	type legacy struct{ Op uint8 }
+	type current struct{ Op Operation }
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(legacy{Op: uint8(OpRemove)})
+	expect.ErrorNil(t, err)
+	var decoded current
+	err = gob.NewDecoder(&buf).Decode(&decoded)
+	expect.ErrorNil(t, err)
+	expect.Equal(t, decoded.Op, OpRemove)
+
+	buf.Reset()
+	err = gob.NewEncoder(&buf).Encode(current{Op: OpSet})
+	expect.ErrorNil(t, err)
+	var decodedLegacy legacy
+	err = gob.NewDecoder(&buf).Decode(&decodedLegacy)
+	expect.ErrorNil(t, err)
+	expect.Equal(t, decodedLegacy.Op, uint8(OpSet))
+}
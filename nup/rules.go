@@ -0,0 +1,253 @@
+package nup
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	// ErrRequires indicates a violation of a Requires rule.
+	ErrRequires = errors.New("required when")
+	// ErrTogether indicates a violation of a Together rule.
+	ErrTogether = errors.New("must change together with")
+	// ErrExclusive indicates a violation of an Exclusive rule.
+	ErrExclusive = errors.New("mutually exclusive with")
+	// ErrAtLeastOneRemains indicates a violation of an AtLeastOneRemains rule.
+	ErrAtLeastOneRemains = errors.New("at least one must remain of")
+)
+
+// Rule is a constraint spanning multiple fields of a patch struct. Use
+// CheckRules to check a patch against a set of rules.
+//
+// Rules identify fields by their JSON keys, e.g. "postal_code", or by JSON
+// pointers for fields of nested patch structs, e.g. "address/postal_code". A
+// field is considered present after applying the patch if the patch sets it,
+// to any value including the zero value, or if the patch leaves it unchanged
+// and the model's current value is non-zero (or for slices, non-empty). A
+// field the patch removes is never present. See CheckRules.
+type Rule struct {
+	paths []string
+	check func(fields []ruleField) FieldErrors
+}
+
+// ruleField is the state of a field of a patch struct when checking a rule.
+type ruleField struct {
+	path string
+	name string
+	// op is the operation the patch performs on the field.
+	op Operation
+	// present indicates whether the field is present after applying the
+	// patch, as defined by Rule.
+	present bool
+}
+
+func (f ruleField) error(err error) *FieldError {
+	return &FieldError{
+		Path:  f.path,
+		Field: f.name,
+		Op:    f.op,
+		Err:   err,
+	}
+}
+
+func newRule(paths []string, check func(fields []ruleField) FieldErrors) Rule {
+	normalized := make([]string, len(paths))
+	for i, path := range paths {
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		normalized[i] = path
+	}
+	return Rule{
+		paths: normalized,
+		check: check,
+	}
+}
+
+// Requires returns a rule that if the patch sets field, then each of the
+// dependencies must be present after applying the patch.
+func Requires(field string, dependencies ...string) Rule {
+	return newRule(append([]string{field}, dependencies...), func(fields []ruleField) FieldErrors {
+		if fields[0].op != OpSet {
+			return nil
+		}
+		var errs FieldErrors
+		for _, dependency := range fields[1:] {
+			if !dependency.present {
+				errs = append(errs, dependency.error(fmt.Errorf("%w %s is set", ErrRequires, fields[0].path)))
+			}
+		}
+		return errs
+	})
+}
+
+// Together returns a rule that if the patch changes (sets or removes) any of
+// the given fields, then it must change all of them.
+func Together(fields ...string) Rule {
+	return newRule(fields, func(fields []ruleField) FieldErrors {
+		var changed, unchanged []ruleField
+		for _, field := range fields {
+			if field.op == OpNoop {
+				unchanged = append(unchanged, field)
+			} else {
+				changed = append(changed, field)
+			}
+		}
+		if len(changed) == 0 {
+			return nil
+		}
+		var errs FieldErrors
+		for _, field := range unchanged {
+			errs = append(errs, field.error(fmt.Errorf("%w %s", ErrTogether, joinPaths(changed))))
+		}
+		return errs
+	})
+}
+
+// Exclusive returns a rule that at most one of the given fields may be present
+// after applying the patch.
+func Exclusive(fields ...string) Rule {
+	return newRule(fields, func(fields []ruleField) FieldErrors {
+		var present []ruleField
+		for _, field := range fields {
+			if field.present {
+				present = append(present, field)
+			}
+		}
+		if len(present) < 2 {
+			return nil
+		}
+		var errs FieldErrors
+		for i, field := range present[1:] {
+			errs = append(errs, field.error(fmt.Errorf("%w %s", ErrExclusive, joinPaths(present[:i+1]))))
+		}
+		return errs
+	})
+}
+
+// AtLeastOneRemains returns a rule that at least one of the given fields must
+// be present after applying the patch. For example, AtLeastOneRemains("email",
+// "phone") rejects a patch that would leave a user with neither an email
+// address nor a phone number.
+func AtLeastOneRemains(fields ...string) Rule {
+	return newRule(fields, func(fields []ruleField) FieldErrors {
+		for _, field := range fields {
+			if field.present {
+				return nil
+			}
+		}
+		// Blame the fields the patch changed, if any.
+		var errs FieldErrors
+		for _, field := range fields {
+			if field.op != OpNoop {
+				errs = append(errs, field.error(fmt.Errorf("%w %s", ErrAtLeastOneRemains, joinPaths(fields))))
+			}
+		}
+		if len(errs) == 0 {
+			errs = append(errs, fields[0].error(fmt.Errorf("%w %s", ErrAtLeastOneRemains, joinPaths(fields))))
+		}
+		return errs
+	})
+}
+
+func joinPaths(fields []ruleField) string {
+	paths := make([]string, len(fields))
+	for i, field := range fields {
+		paths[i] = field.path
+	}
+	return strings.Join(paths, ", ")
+}
+
+// CheckRules checks patch, a patch struct or a pointer to one, against the
+// given rules, returning all violations as a FieldErrors.
+//
+// model, if non-nil, is the current state that the patch would be applied to:
+// a struct or pointer to a struct suitable for ApplyPatch. Rules concerning
+// whether fields are present after applying the patch consider the model's
+// current values for fields the patch doesn't change. If model is nil, only
+// the fields the patch sets are considered present. Either way, a field the
+// patch sets is present even if it's set to the zero value, e.g. Set(0).
+// CheckRules does not modify model.
+//
+// CheckRules returns an error that is not a FieldErrors if a rule names a field
+// that patch doesn't have or that model doesn't have a matching field for, or
+// if a field of patch is a pointer to a patch struct, which isn't supported.
+func CheckRules(patch, model interface{}, rules ...Rule) error {
+	patchValue, ok := structValue(patch)
+	if !ok {
+		return fmt.Errorf("nup: CheckRules requires a patch struct, not %T", patch)
+	}
+	if err := checkPatchType(patchValue.Type()); err != nil {
+		return err
+	}
+	var modelValue reflect.Value
+	if model != nil {
+		if modelValue, ok = structValue(model); !ok {
+			return fmt.Errorf("nup: CheckRules requires a struct model, not %T", model)
+		}
+	}
+
+	fields := map[string]ruleField{}
+	var err error
+	walkUpdates(patchValue, "", "", func(field patchField) {
+		if err != nil {
+			return
+		}
+		update := field.value.Interface().(updateMarshaller)
+		state := ruleField{
+			path:    field.path,
+			name:    field.name,
+			op:      update.Operation(),
+			present: update.Operation() == OpSet,
+		}
+		if modelValue.IsValid() {
+			state.present, err = presentAfter(modelValue, field)
+		}
+		fields[field.path] = state
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs FieldErrors
+	for _, rule := range rules {
+		ruleFields := make([]ruleField, len(rule.paths))
+		for i, path := range rule.paths {
+			field, ok := fields[path]
+			if !ok {
+				return fmt.Errorf("nup: rule refers to unknown field %s", path)
+			}
+			ruleFields[i] = field
+		}
+		errs = append(errs, rule.check(ruleFields)...)
+	}
+	return errs.Err()
+}
+
+// presentAfter returns whether the model field corresponding to the patch
+// field would be present after applying the patch field's update, without
+// modifying the model. As without a model, the field is present if the update
+// sets it and absent if it removes it; only a no-op consults the model's
+// current value.
+func presentAfter(model reflect.Value, field patchField) (bool, error) {
+	fieldType, ok := modelFieldType(model.Type(), field.name)
+	if !ok {
+		return false, fmt.Errorf("nup: model %s has no field %s", model.Type(), field.name)
+	}
+	result := reflect.New(fieldType).Elem()
+	if current, ok := lookupModelField(model, field.name, false); ok {
+		result.Set(current)
+	}
+	if !field.value.Interface().(updateApplier).applyTo(result) {
+		return false, fmt.Errorf("nup: cannot apply %s to field %s of type %s", field.Type, field.name, fieldType)
+	}
+	if op := field.value.Interface().(updateMarshaller).Operation(); op != OpNoop {
+		return op == OpSet, nil
+	}
+	if result.Kind() == reflect.Slice {
+		return result.Len() > 0, nil
+	}
+	return !result.IsZero(), nil
+}
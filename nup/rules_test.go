@@ -0,0 +1,247 @@
+package nup
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nicheinc/expect"
+)
+
+type testContactPatch struct {
+	Email      Update[string] `json:"email"`
+	Phone      Update[string] `json:"phone"`
+	Country    Update[string] `json:"country"`
+	PostalCode Update[string] `json:"postal_code"`
+	Start      Update[int]    `json:"start"`
+	End        Update[int]    `json:"end"`
+}
+
+type testContact struct {
+	Email      *string
+	Phone      *string
+	Country    string
+	PostalCode string
+	Start      int
+	End        int
+}
+
+func TestCheckRules(t *testing.T) {
+	var (
+		email = "alice@example.com"
+		phone = "555-0100"
+	)
+	rules := []Rule{
+		Requires("country", "postal_code"),
+		Together("start", "end"),
+		Exclusive("/country", "/phone"),
+		AtLeastOneRemains("email", "phone"),
+	}
+	testCases := []struct {
+		name       string
+		patch      testContactPatch
+		model      interface{}
+		errorCheck expect.ErrorCheck
+	}{
+		{
+			name:       "EmptyPatchValidModel",
+			patch:      testContactPatch{},
+			model:      testContact{Email: &email},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "RequiresSatisfiedByPatch",
+			patch: testContactPatch{
+				Country:    Set("US"),
+				PostalCode: Set("12345"),
+			},
+			model:      &testContact{Email: &email},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "RequiresSatisfiedByModel",
+			patch: testContactPatch{
+				Country: Set("US"),
+			},
+			model:      &testContact{Email: &email, PostalCode: "12345"},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "RequiresViolated",
+			patch: testContactPatch{
+				Country: Set("US"),
+			},
+			model:      &testContact{Email: &email},
+			errorCheck: expect.ErrorIs(ErrRequires),
+		},
+		{
+			name: "RequiresSatisfiedBySetZero",
+			patch: testContactPatch{
+				Country:    Set("US"),
+				PostalCode: Set(""),
+			},
+			model:      &testContact{Email: &email},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "RequiresSatisfiedBySetZeroWithoutModel",
+			patch: testContactPatch{
+				Email:      Set(email),
+				Country:    Set("US"),
+				PostalCode: Set(""),
+			},
+			model:      nil,
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "ExclusiveViolatedBySetZero",
+			patch: testContactPatch{
+				Country:    Set(""),
+				PostalCode: Set("12345"),
+				Phone:      Set(phone),
+			},
+			model:      &testContact{Email: &email},
+			errorCheck: expect.ErrorIs(ErrExclusive),
+		},
+		{
+			name: "ExclusiveViolatedBySetZeroWithoutModel",
+			patch: testContactPatch{
+				Country:    Set(""),
+				PostalCode: Set("12345"),
+				Phone:      Set(phone),
+			},
+			model:      nil,
+			errorCheck: expect.ErrorIs(ErrExclusive),
+		},
+		{
+			name: "RequiresViolatedByRemoval",
+			patch: testContactPatch{
+				Country:    Set("US"),
+				PostalCode: Remove[string](),
+			},
+			model:      &testContact{Email: &email, PostalCode: "12345"},
+			errorCheck: expect.ErrorIs(ErrRequires),
+		},
+		{
+			name: "TogetherSatisfied",
+			patch: testContactPatch{
+				Start: Set(1),
+				End:   Remove[int](),
+			},
+			model:      &testContact{Email: &email},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "TogetherViolated",
+			patch: testContactPatch{
+				Start: Set(1),
+			},
+			model:      &testContact{Email: &email},
+			errorCheck: expect.ErrorIs(ErrTogether),
+		},
+		{
+			name: "ExclusiveViolatedWithModel",
+			patch: testContactPatch{
+				Phone: Set(phone),
+			},
+			model:      &testContact{Country: "US", PostalCode: "12345"},
+			errorCheck: expect.ErrorIs(ErrExclusive),
+		},
+		{
+			name: "AtLeastOneRemainsViolated",
+			patch: testContactPatch{
+				Email: Remove[string](),
+			},
+			model:      &testContact{Email: &email},
+			errorCheck: expect.ErrorIs(ErrAtLeastOneRemains),
+		},
+		{
+			name: "AtLeastOneRemainsSatisfiedByModel",
+			patch: testContactPatch{
+				Email: Remove[string](),
+			},
+			model:      &testContact{Email: &email, Phone: &phone},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "WithoutModel",
+			patch: testContactPatch{
+				Email:   Set(email),
+				Country: Set("US"),
+				Start:   Set(1),
+			},
+			model:      nil,
+			errorCheck: expect.ErrorIsAll(ErrRequires, ErrTogether),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := CheckRules(testCase.patch, testCase.model, rules...)
+			testCase.errorCheck(t, err)
+		})
+	}
+}
+
+func TestCheckRules_FieldErrors(t *testing.T) {
+	email := "alice@example.com"
+	patch := testContactPatch{
+		Email: Remove[string](),
+	}
+	model := testContact{Email: &email}
+	err := CheckRules(patch, &model, AtLeastOneRemains("email", "phone"))
+	var errs FieldErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected FieldErrors, got %T", err)
+	}
+	expect.Equal(t, len(errs), 1)
+	expect.Equal(t, errs[0].Error(), "nup: /email: cannot remove: at least one must remain of /email, /phone")
+	expect.Equal(t, errs[0].Field, "Email")
+	// The model must be left untouched.
+	expect.Equal(t, model.Email, &email)
+}
+
+func TestCheckRules_Errors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		patch interface{}
+		model interface{}
+		rule  Rule
+	}{
+		{
+			name:  "PatchNotStruct",
+			patch: 5,
+			rule:  Together("email", "phone"),
+		},
+		{
+			name:  "ModelNotStruct",
+			patch: testContactPatch{},
+			model: 5,
+			rule:  Together("email", "phone"),
+		},
+		{
+			name:  "UnknownField",
+			patch: testContactPatch{},
+			rule:  Together("email", "fax"),
+		},
+		{
+			name:  "ModelMissingField",
+			patch: testContactPatch{},
+			model: struct{ Email string }{},
+			rule:  Together("email", "phone"),
+		},
+		{
+			name:  "PointerToPatch",
+			patch: testPointerPatch{},
+			rule:  Together("name"),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := CheckRules(testCase.patch, testCase.model, testCase.rule)
+			expect.ErrorNonNil(t, err)
+			var errs FieldErrors
+			expect.Equal(t, errors.As(err, &errs), false)
+		})
+	}
+}
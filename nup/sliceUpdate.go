@@ -1,8 +1,15 @@
 package nup
 
 import (
+	"bytes"
+	"database/sql"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
 	"fmt"
+	"reflect"
+	"strings"
 )
 
 // SliceUpdate represents an update to a slice field. It may set, remove, or
@@ -43,6 +50,35 @@ func SliceRemoveOrSet[T comparable](value []T) SliceUpdate[T] {
 	}
 }
 
+// SliceFromNull returns a slice update corresponding to the given sql.Null
+// value. If present is false, the update is a no-op; otherwise, it removes if
+// the value is invalid (NULL) or sets to the value if it's valid. SliceFromNull
+// is the inverse of SliceUpdate.ToNull.
+func SliceFromNull[T comparable](value sql.Null[[]T], present bool) SliceUpdate[T] {
+	switch {
+	case !present:
+		return SliceNoop[T]()
+	case !value.Valid:
+		return SliceRemove[T]()
+	default:
+		return SliceUpdate[T]{
+			op:    OpSet,
+			value: value.V,
+		}
+	}
+}
+
+// SliceFromPtr returns a slice update corresponding to the given slice pointer,
+// following the convention that a nil *[]T means "no change", and otherwise the
+// slice it points to is passed to SliceRemoveOrSet. SliceFromPtr is the inverse
+// of SliceUpdate.ToPtr.
+func SliceFromPtr[T comparable](ptr *[]T) SliceUpdate[T] {
+	if ptr == nil {
+		return SliceNoop[T]()
+	}
+	return SliceRemoveOrSet(*ptr)
+}
+
 // ValueOperation returns a shallow copy of the value this update sets fields to
 // (if any) and the operation this update performs: no-op, remove, or set. If
 // this update is not a set operation, then the returned value is always nil;
@@ -99,6 +135,30 @@ func (u SliceUpdate[T]) ValueOrNil() []T {
 	return u.value
 }
 
+// ToNull returns this update as an sql.Null value, which is valid if the update
+// is a set operation, along with whether the update is a change (i.e., not a
+// no-op). ToNull is the inverse of SliceFromNull.
+func (u SliceUpdate[T]) ToNull() (value sql.Null[[]T], present bool) {
+	if u.op == OpSet {
+		value = sql.Null[[]T]{
+			V:     u.value,
+			Valid: true,
+		}
+	}
+	return value, u.op != OpNoop
+}
+
+// ToPtr returns this update as a pointer to a slice: nil if the update is a
+// no-op, a pointer to a nil slice if it's a removal, or a pointer to the
+// update's value if it's a set operation. ToPtr is the inverse of SliceFromPtr.
+func (u SliceUpdate[T]) ToPtr() *[]T {
+	if u.op == OpNoop {
+		return nil
+	}
+	value := u.ValueOrNil()
+	return &value
+}
+
 // Apply returns the result of applying the update to the given value. The
 // result is the given value if the update is a no-op, nil if it's a removal, or
 // a shallow copy of the update's contained value if it's a set operation.
@@ -141,6 +201,246 @@ func (u *SliceUpdate[T]) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &u.value)
 }
 
+// MarshalXML implements xml.Marshaler. Like encoding/xml does for slices, it
+// marshals a set operation as a sequence of elements, one per element of the
+// value, so a set operation to an empty slice writes nothing. It marshals a
+// removal as a single empty element with the attribute xsi:nil="true",
+// declaring the xsi namespace, and writes nothing for a no-op.
+func (u SliceUpdate[T]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	switch u.op {
+	case OpNoop:
+		return nil
+	case OpRemove:
+		return encodeNilElement(e, start)
+	default: // Set
+		for _, element := range u.value {
+			if err := e.EncodeElement(element, start); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// UnmarshalXML implements xml.Unmarshaler. Since encoding/xml calls it once
+// per element, it appends each element to the value of a set operation. An
+// element with the attribute xsi:nil="true" is unmarshalled as a removal, while
+// an absent element remains a no-op. The xsi prefix is recognized even if the
+// document doesn't declare it.
+func (u *SliceUpdate[T]) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	if isNilElement(start) {
+		*u = SliceRemove[T]()
+		return d.Skip()
+	}
+	var element T
+	if err := d.DecodeElement(&element, &start); err != nil {
+		return err
+	}
+	if u.op != OpSet {
+		*u = SliceRemoveOrSet([]T{})
+	}
+	u.value = append(u.value, element)
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. Unlike JSON, the binary
+// form distinguishes all three operations wherever the update appears, e.g.
+// in a slice. It consists of the Operation as a single byte followed, for a
+// set operation, by the encoding/gob encoding of the value.
+func (u SliceUpdate[T]) MarshalBinary() ([]byte, error) {
+	data := []byte{byte(u.op)}
+	if u.op != OpSet {
+		return data, nil
+	}
+	data, err := appendBinaryValue(data, u.value)
+	if err != nil {
+		return nil, fmt.Errorf("nup: %w", err)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding the binary
+// form written by MarshalBinary.
+func (u *SliceUpdate[T]) UnmarshalBinary(data []byte) error {
+	op, valueData, err := parseBinaryOperation(data)
+	if err != nil {
+		return err
+	}
+	if op != OpSet {
+		*u = SliceUpdate[T]{op: op}
+		return nil
+	}
+	var value []T
+	if err := parseBinaryValue(valueData, &value); err != nil {
+		return fmt.Errorf("nup: %w", err)
+	}
+	if value == nil {
+		// encoding/gob doesn't distinguish an empty slice from nil.
+		value = []T{}
+	}
+	*u = SliceRemoveOrSet(value)
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder, which encoding/gob requires since
+// SliceUpdate's fields are unexported. It's equivalent to MarshalBinary.
+func (u SliceUpdate[T]) GobEncode() ([]byte, error) {
+	return u.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder. It's equivalent to UnmarshalBinary.
+func (u *SliceUpdate[T]) GobDecode(data []byte) error {
+	return u.UnmarshalBinary(data)
+}
+
+// MarshalYAML implements the Marshaler interface of yaml.v2, yaml.v3, and
+// similar libraries. Like MarshalJSON, it marshals a set operation as its value
+// and a removal as null. To omit no-ops, use the omitempty YAML struct tag
+// option, which respects IsZero.
+func (u SliceUpdate[T]) MarshalYAML() (interface{}, error) {
+	if u.op == OpSet {
+		return u.value, nil
+	}
+	return nil, nil
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of yaml.v2 and similar
+// libraries, which call it with a function that decodes the YAML value into its
+// argument. It unmarshals a value, including an empty sequence, as a set
+// operation, while an absent field remains a no-op.
+//
+// As with Update.UnmarshalYAML, removals are only partly supported: yaml.v2
+// and yaml.v3 never call UnmarshalYAML for null (~) values, so with those
+// libraries null decodes as a no-op rather than a removal.
+func (u *SliceUpdate[T]) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value *[]T
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	switch {
+	case value == nil:
+		*u = SliceRemove[T]()
+	case *value == nil:
+		// Distinguish an empty sequence from null.
+		*u = SliceRemoveOrSet([]T{})
+	default:
+		*u = SliceRemoveOrSet(*value)
+	}
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler using the default
+// TextOptions. The elements of a set operation's value are marshalled like the
+// value of an Update and joined with the separator, and an empty slice is
+// represented by the Empty token.
+func (u SliceUpdate[T]) MarshalText() ([]byte, error) {
+	return u.marshalText(TextOptions{}.withDefaults())
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using the default
+// TextOptions. Text other than the tokens is split on the separator, and each
+// element is unmarshalled like the value of an Update.
+func (u *SliceUpdate[T]) UnmarshalText(text []byte) error {
+	return u.unmarshalText(text, TextOptions{}.withDefaults())
+}
+
+// marshalText implements textCodec.
+func (u *SliceUpdate[T]) marshalText(o TextOptions) ([]byte, error) {
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	if token, ok := o.token(u.op); ok {
+		return []byte(token), nil
+	}
+	if len(u.value) == 0 {
+		return []byte(o.Empty), nil
+	}
+	var text []byte
+	for i, element := range u.value {
+		elementText, err := formatText(element)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Contains(elementText, []byte(o.Separator)) {
+			return nil, fmt.Errorf("nup: cannot marshal slice element as text %q, which contains the separator %q", elementText, o.Separator)
+		}
+		if i > 0 {
+			text = append(text, o.Separator...)
+		}
+		text = append(text, elementText...)
+	}
+	if string(text) == o.Empty {
+		return nil, fmt.Errorf("nup: cannot marshal set value as text %q, which is reserved for an empty slice", text)
+	}
+	return o.marshalValueText(text)
+}
+
+// unmarshalText implements textCodec.
+func (u *SliceUpdate[T]) unmarshalText(text []byte, o TextOptions) error {
+	if err := o.validate(); err != nil {
+		return err
+	}
+	op := o.operation(text)
+	if op != OpSet {
+		*u = SliceUpdate[T]{op: op}
+		return nil
+	}
+	elements := o.splitText(text)
+	value := make([]T, len(elements))
+	for i, element := range elements {
+		if err := parseText([]byte(element), &value[i]); err != nil {
+			return err
+		}
+	}
+	*u = SliceRemoveOrSet(value)
+	return nil
+}
+
+// decodeValues implements valuesCodec.
+func (u *SliceUpdate[T]) decodeValues(values []string, null string) error {
+	switch valuesOperation(values, null) {
+	case OpNoop:
+		*u = SliceNoop[T]()
+		return nil
+	case OpRemove:
+		*u = SliceRemove[T]()
+		return nil
+	}
+	value := make([]T, len(values))
+	for i, element := range values {
+		if err := parseText([]byte(element), &value[i]); err != nil {
+			return err
+		}
+	}
+	*u = SliceRemoveOrSet(value)
+	return nil
+}
+
+// encodeValues implements valuesCodec.
+func (u *SliceUpdate[T]) encodeValues(null string) ([]string, error) {
+	switch u.op {
+	case OpNoop:
+		return nil, nil
+	case OpRemove:
+		return []string{null}, nil
+	}
+	if len(u.value) == 0 {
+		return nil, errors.New("cannot encode an empty slice")
+	}
+	values := make([]string, len(u.value))
+	for i, element := range u.value {
+		text, err := formatText(element)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = string(text)
+	}
+	if len(values) == 1 && values[0] == null {
+		return nil, errNullValue([]byte(values[0]), null)
+	}
+	return values, nil
+}
+
 // IsSetTo returns whether the update sets to a value that is element-wise equal
 // to the given value.
 func (u SliceUpdate[T]) IsSetTo(value []T) bool {
@@ -195,3 +495,72 @@ func (u SliceUpdate[T]) interfaceValue() interface{} {
 	}
 	return nil
 }
+
+// applyTo implements updateApplier, which ApplyPatch uses to apply updates to
+// struct fields of type []T.
+func (u SliceUpdate[T]) applyTo(field reflect.Value) bool {
+	switch dst := field.Addr().Interface().(type) {
+	case *[]T:
+		*dst = u.Apply(*dst)
+	default:
+		return false
+	}
+	return true
+}
+
+// changes implements updateApplier, which DiffPatch uses to check whether
+// updates would change struct fields of type []T.
+func (u SliceUpdate[T]) changes(field reflect.Value) bool {
+	src, ok := field.Interface().([]T)
+	return ok && u.Diff(src).IsChange()
+}
+
+// assignFrom implements updateAssigner, which FromFieldMask uses to build
+// updates from struct fields of type []T. An empty slice yields a removal.
+func (u *SliceUpdate[T]) assignFrom(field reflect.Value) bool {
+	src, ok := field.Interface().([]T)
+	if !ok {
+		return false
+	}
+	if len(src) == 0 {
+		*u = SliceRemove[T]()
+	} else {
+		*u = SliceRemoveOrSet(src)
+	}
+	return true
+}
+
+// assignValue implements updateAssigner, which DiffModels uses to build
+// updates from struct fields of type []T. Unlike assignFrom, an empty, non-nil
+// slice yields a set operation; only a nil slice yields a removal.
+func (u *SliceUpdate[T]) assignValue(field reflect.Value) bool {
+	src, ok := field.Interface().([]T)
+	if !ok {
+		return false
+	}
+	*u = SliceRemoveOrSet(src)
+	return true
+}
+
+// flagValue implements flagger.
+func (u *SliceUpdate[T]) flagValue() flag.Value {
+	return SliceFlag(u)
+}
+
+// clearFlagValue implements flagger.
+func (u *SliceUpdate[T]) clearFlagValue() flag.Value {
+	return SliceClearFlag(u)
+}
+
+// decodeEnv implements envCodec.
+func (u *SliceUpdate[T]) decodeEnv(value string, opts EnvOptions) error {
+	switch value {
+	case opts.Null:
+		*u = SliceRemove[T]()
+		return nil
+	case "":
+		*u = SliceRemoveOrSet([]T{})
+		return nil
+	}
+	return u.decodeValues(strings.Split(value, opts.Separator), opts.Null)
+}
@@ -1,6 +1,7 @@
 package nup
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"testing"
@@ -521,3 +522,94 @@ func TestSliceUpdate_Equal(t *testing.T) {
 		})
 	}
 }
+
+func TestSliceUpdate_NullConversions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		update  SliceUpdate[int]
+		null    sql.Null[[]int]
+		present bool
+	}{
+		{
+			name:    "Noop",
+			update:  SliceNoop[int](),
+			null:    sql.Null[[]int]{},
+			present: false,
+		},
+		{
+			name:    "Remove",
+			update:  SliceRemove[int](),
+			null:    sql.Null[[]int]{},
+			present: true,
+		},
+		{
+			name:    "SetEmpty",
+			update:  SliceRemoveOrSet([]int{}),
+			null:    sql.Null[[]int]{V: []int{}, Valid: true},
+			present: true,
+		},
+		{
+			name:    "Set",
+			update:  SliceRemoveOrSet(testSlice2),
+			null:    sql.Null[[]int]{V: testSlice2, Valid: true},
+			present: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			null, present := testCase.update.ToNull()
+			expect.Equal(t, null, testCase.null)
+			expect.Equal(t, present, testCase.present)
+			expect.Equal(t, SliceFromNull(null, present), testCase.update)
+		})
+	}
+}
+
+func TestSliceFromNull_NotPresent(t *testing.T) {
+	actual := SliceFromNull(sql.Null[[]int]{V: testSlice1, Valid: true}, false)
+	expect.Equal(t, actual, SliceNoop[int]())
+}
+
+func TestSliceUpdate_PtrConversions(t *testing.T) {
+	var (
+		empty    = []int{}
+		value    = testSlice2
+		nilSlice []int
+	)
+	testCases := []struct {
+		name   string
+		update SliceUpdate[int]
+		ptr    *[]int
+	}{
+		{
+			name:   "Noop",
+			update: SliceNoop[int](),
+			ptr:    nil,
+		},
+		{
+			name:   "Remove",
+			update: SliceRemove[int](),
+			ptr:    &nilSlice,
+		},
+		{
+			name:   "SetEmpty",
+			update: SliceRemoveOrSet([]int{}),
+			ptr:    &empty,
+		},
+		{
+			name:   "Set",
+			update: SliceRemoveOrSet(testSlice2),
+			ptr:    &value,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ptr := testCase.update.ToPtr()
+			expect.Equal(t, ptr, testCase.ptr)
+			expect.Equal(t, SliceFromPtr(ptr), testCase.update)
+			expect.Equal(t, SliceFromPtr(testCase.ptr), testCase.update)
+		})
+	}
+}
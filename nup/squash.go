@@ -0,0 +1,38 @@
+package nup
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// SquashPatch combines two patches of the same type, updating the patch struct
+// pointed to by patch so that applying it is equivalent to applying the
+// original patch followed by next, which may be a patch struct or a pointer to
+// one. That is, each field that next changes (sets or removes) replaces the
+// corresponding field of patch, and fields that next leaves as no-ops are
+// unchanged. Fields of patch other than Update and SliceUpdate fields are
+// unchanged.
+//
+// SquashPatch can be used to combine layers of configuration or successive
+// edits into a single patch.
+func SquashPatch(patch, next interface{}) error {
+	patchValue := reflect.ValueOf(patch)
+	if patchValue.Kind() != reflect.Pointer || patchValue.IsNil() || patchValue.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("nup: SquashPatch requires a non-nil pointer to a patch struct, not %T", patch)
+	}
+	nextValue, ok := structValue(next)
+	if !ok || nextValue.Type() != patchValue.Elem().Type() {
+		return fmt.Errorf("nup: cannot squash %T onto %T", next, patch)
+	}
+	var (
+		fields     = patchFields(patch)
+		nextFields = patchFields(next)
+	)
+	for i, field := range fields {
+		nextField := nextFields[i].value
+		if nextField.Interface().(updateMarshaller).IsChange() {
+			field.value.Set(nextField)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package nup
+
+import (
+	"testing"
+
+	"github.com/nicheinc/expect"
+)
+
+func TestSquashPatch(t *testing.T) {
+	testCases := []struct {
+		name     string
+		patch    testFormPatch
+		next     testFormPatch
+		expected testFormPatch
+	}{
+		{
+			name:     "Noops",
+			patch:    testFormPatch{},
+			next:     testFormPatch{},
+			expected: testFormPatch{},
+		},
+		{
+			name: "NextNoops",
+			patch: testFormPatch{
+				Name: Set("Alice"),
+				Tags: SliceRemove[string](),
+			},
+			next: testFormPatch{},
+			expected: testFormPatch{
+				Name: Set("Alice"),
+				Tags: SliceRemove[string](),
+			},
+		},
+		{
+			name: "NextChanges",
+			patch: testFormPatch{
+				Name:    Set("Alice"),
+				Age:     Set(30),
+				Address: testAddressPatch{City: Set("Paris")},
+			},
+			next: testFormPatch{
+				Name:    Remove[string](),
+				Tags:    SliceRemoveOrSet([]string{"a"}),
+				Address: testAddressPatch{City: Set("Lyon"), Zip: Remove[string]()},
+			},
+			expected: testFormPatch{
+				Name:    Remove[string](),
+				Age:     Set(30),
+				Tags:    SliceRemoveOrSet([]string{"a"}),
+				Address: testAddressPatch{City: Set("Lyon"), Zip: Remove[string]()},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual := testCase.patch
+			err := SquashPatch(&actual, testCase.next)
+			expect.ErrorNil(t, err)
+			expect.Equal(t, actual, testCase.expected)
+		})
+	}
+}
+
+// TestSquashPatch_Apply ensures that applying a squashed patch is equivalent to
+// applying its parts in order.
+func TestSquashPatch_Apply(t *testing.T) {
+	var (
+		first  = testPatch{Name: Set("Alice"), Age: Set(30), Tags: SliceRemoveOrSet([]int{1})}
+		second = testPatch{Age: Remove[int](), Address: testAddressPatch{City: Set("Paris")}}
+		age    = 20
+		model  = testModel{Name: "Bob", Age: &age}
+	)
+	sequential := model
+	expect.ErrorNil(t, ApplyPatch(&sequential, first))
+	expect.ErrorNil(t, ApplyPatch(&sequential, second))
+
+	squashed := first
+	expect.ErrorNil(t, SquashPatch(&squashed, &second))
+	combined := model
+	expect.ErrorNil(t, ApplyPatch(&combined, squashed))
+
+	expect.Equal(t, combined, sequential)
+}
+
+func TestSquashPatch_Errors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		patch interface{}
+		next  interface{}
+	}{
+		{
+			name:  "PatchNotPointer",
+			patch: testFormPatch{},
+			next:  testFormPatch{},
+		},
+		{
+			name:  "MismatchedTypes",
+			patch: &testFormPatch{},
+			next:  testPatch{},
+		},
+		{
+			name:  "NextNotStruct",
+			patch: &testFormPatch{},
+			next:  5,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := SquashPatch(testCase.patch, testCase.next)
+			expect.ErrorNonNil(t, err)
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package nup
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// TextOptions configures how updates are represented as text, e.g. in CSV
+// files, environment variables, or query strings. Unlike JSON, text has no
+// natural way to represent an absent value, so a no-op is represented by an
+// explicit token, Noop, just as a removal is represented by Null and a set
+// operation to an empty slice by Empty.
+//
+// The zero value is ready to use: each empty field takes its default, so that
+// a no-op ("undefined"), a removal ("null"), the empty string (""), and an
+// empty slice ("[]") can all be told apart. Because the tokens are ordinary
+// text, a set value whose text is equal to one of them would be ambiguous, so
+// marshalling such a value fails. For example, by default, Set("null") cannot
+// be marshalled as text. Choose tokens that don't occur in your data.
+type TextOptions struct {
+	// Null is the text representing a removal. If it's empty, the default,
+	// a removal is represented by "null".
+	Null string
+	// Noop is the text representing a no-op. If it's empty, the default,
+	// a no-op is represented by "undefined".
+	Noop string
+	// Empty is the text representing a set operation to an empty slice. If
+	// it's empty, the default, an empty slice is represented by "[]". Empty
+	// text, on the other hand, represents a slice with a single element whose
+	// text is empty, e.g. []string{""}.
+	Empty string
+	// Separator separates the elements of a SliceUpdate's value. An element
+	// whose text contains the separator cannot be marshalled. If it's empty,
+	// the default, elements are separated by commas.
+	Separator string
+}
+
+// withDefaults returns o with its empty fields set to their defaults.
+func (o TextOptions) withDefaults() TextOptions {
+	if o.Null == "" {
+		o.Null = "null"
+	}
+	if o.Noop == "" {
+		o.Noop = "undefined"
+	}
+	if o.Empty == "" {
+		o.Empty = "[]"
+	}
+	if o.Separator == "" {
+		o.Separator = ","
+	}
+	return o
+}
+
+// textCodec is implemented by Update and SliceUpdate pointers, so that
+// TextOptions can marshal and unmarshal them.
+type textCodec interface {
+	marshalText(o TextOptions) ([]byte, error)
+	unmarshalText(text []byte, o TextOptions) error
+}
+
+// MarshalText returns the text representation of u, which must be an Update or
+// SliceUpdate or a pointer to one, according to o.
+func (o TextOptions) MarshalText(u interface{}) ([]byte, error) {
+	value := reflect.ValueOf(u)
+	if value.IsValid() && value.Kind() != reflect.Pointer {
+		// Copy the value to find the pointer method.
+		ptr := reflect.New(value.Type())
+		ptr.Elem().Set(value)
+		value = ptr
+	}
+	if !value.IsValid() || value.IsNil() {
+		return nil, fmt.Errorf("nup: cannot marshal %T as an update", u)
+	}
+	codec, ok := value.Interface().(textCodec)
+	if !ok {
+		return nil, fmt.Errorf("nup: cannot marshal %T as an update", u)
+	}
+	return codec.marshalText(o.withDefaults())
+}
+
+// UnmarshalText sets u, which must be a pointer to an Update or SliceUpdate,
+// from its text representation according to o.
+func (o TextOptions) UnmarshalText(text []byte, u interface{}) error {
+	codec, ok := u.(textCodec)
+	if !ok || reflect.ValueOf(codec).IsNil() {
+		return fmt.Errorf("nup: cannot unmarshal text into %T", u)
+	}
+	return codec.unmarshalText(text, o.withDefaults())
+}
+
+// validate returns an error if the tokens of o are ambiguous.
+func (o TextOptions) validate() error {
+	switch {
+	case o.Null == o.Noop:
+		return fmt.Errorf("nup: text options use %q for both null and no-op", o.Null)
+	case o.Empty == o.Null:
+		return fmt.Errorf("nup: text options use %q for both null and an empty slice", o.Null)
+	case o.Empty == o.Noop:
+		return fmt.Errorf("nup: text options use %q for both no-op and an empty slice", o.Noop)
+	}
+	return nil
+}
+
+// token returns the text token for a no-op or removal, or false for a set
+// operation.
+func (o TextOptions) token(op Operation) (string, bool) {
+	switch op {
+	case OpNoop:
+		return o.Noop, true
+	case OpRemove:
+		return o.Null, true
+	default:
+		return "", false
+	}
+}
+
+// operation returns the operation represented by text, which is OpSet unless
+// text is one of the tokens.
+func (o TextOptions) operation(text []byte) Operation {
+	switch string(text) {
+	case o.Noop:
+		return OpNoop
+	case o.Null:
+		return OpRemove
+	default:
+		return OpSet
+	}
+}
+
+// marshalValueText marshals a set value to text, checking that it doesn't
+// collide with the tokens.
+func (o TextOptions) marshalValueText(value []byte) ([]byte, error) {
+	if op := o.operation(value); op != OpSet {
+		return nil, fmt.Errorf("nup: cannot marshal set value as text %q, which is reserved for %s", value, op)
+	}
+	return value, nil
+}
+
+// formatText returns the text representation of value, using its MarshalText
+// method if it has one or else strconv for basic kinds.
+func formatText(value interface{}) ([]byte, error) {
+	if marshaler, ok := value.(encoding.TextMarshaler); ok {
+		return marshaler.MarshalText()
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String:
+		return []byte(v.String()), nil
+	case reflect.Bool:
+		return strconv.AppendBool(nil, v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(nil, v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.AppendUint(nil, v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.AppendFloat(nil, v.Float(), 'g', -1, v.Type().Bits()), nil
+	default:
+		return nil, fmt.Errorf("nup: cannot marshal %T as text", value)
+	}
+}
+
+// parseText sets the value pointed to by ptr from its text representation,
+// using its UnmarshalText method if it has one or else strconv for basic kinds.
+func parseText(text []byte, ptr interface{}) error {
+	if unmarshaler, ok := ptr.(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText(text)
+	}
+	var (
+		v   = reflect.ValueOf(ptr).Elem()
+		s   = string(text)
+		err error
+	)
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(s)
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		i, err = strconv.ParseInt(s, 10, v.Type().Bits())
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		u, err = strconv.ParseUint(s, 10, v.Type().Bits())
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		f, err = strconv.ParseFloat(s, v.Type().Bits())
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("nup: cannot unmarshal text into %s", v.Type())
+	}
+	if err != nil {
+		// Unwrap the *strconv.NumError, whose message repeats the input.
+		var numErr *strconv.NumError
+		if errors.As(err, &numErr) {
+			err = numErr.Err
+		}
+		return fmt.Errorf("nup: cannot unmarshal %q into %s: %w", text, v.Type(), err)
+	}
+	return nil
+}
+
+// splitText splits the text of a SliceUpdate's value into its elements. The
+// Empty token represents an empty slice.
+func (o TextOptions) splitText(text []byte) []string {
+	if string(text) == o.Empty {
+		return []string{}
+	}
+	return strings.Split(string(text), o.Separator)
+}
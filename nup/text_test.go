@@ -0,0 +1,338 @@
+package nup
+
+import (
+	"encoding"
+	"net/netip"
+	"strconv"
+	"testing"
+
+	"github.com/nicheinc/expect"
+)
+
+// Ensure implementation of the encoding interfaces.
+var (
+	_ encoding.TextMarshaler   = Update[int]{}
+	_ encoding.TextUnmarshaler = &Update[int]{}
+	_ encoding.TextMarshaler   = SliceUpdate[int]{}
+	_ encoding.TextUnmarshaler = &SliceUpdate[int]{}
+)
+
+type testLevel int
+
+func TestUpdate_MarshalText(t *testing.T) {
+	testCases := []struct {
+		name       string
+		update     encoding.TextMarshaler
+		expected   string
+		errorCheck expect.ErrorCheck
+	}{
+		{
+			name:       "Noop",
+			update:     Noop[int](),
+			expected:   "undefined",
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "Remove",
+			update:     Remove[int](),
+			expected:   "null",
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "SetInt",
+			update:     Set(-42),
+			expected:   "-42",
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "SetUint",
+			update:     Set(uint8(255)),
+			expected:   "255",
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "SetFloat",
+			update:     Set(1.5),
+			expected:   "1.5",
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "SetBool",
+			update:     Set(true),
+			expected:   "true",
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "SetString",
+			update:     Set("hello"),
+			expected:   "hello",
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "SetNamedType",
+			update:     Set(testLevel(3)),
+			expected:   "3",
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "SetTextMarshaler",
+			update:     Set(netip.MustParseAddr("192.0.2.1")),
+			expected:   "192.0.2.1",
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "SetEmptyString",
+			update:     Set(""),
+			expected:   "",
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "SetNoopToken",
+			update:     Set("undefined"),
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name:       "SetNullToken",
+			update:     Set("null"),
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name:       "Unsupported",
+			update:     Set(struct{ A int }{1}),
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name:       "SliceNoop",
+			update:     SliceNoop[int](),
+			expected:   "undefined",
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "SliceRemove",
+			update:     SliceRemove[int](),
+			expected:   "null",
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "SliceSet",
+			update:     SliceRemoveOrSet([]int{1, 2, 3}),
+			expected:   "1,2,3",
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "SliceSetEmpty",
+			update:     SliceRemoveOrSet([]int{}),
+			expected:   "[]",
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "SliceSetEmptyString",
+			update:     SliceRemoveOrSet([]string{""}),
+			expected:   "",
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "SliceSetEmptyToken",
+			update:     SliceRemoveOrSet([]string{"[]"}),
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name:       "SliceSetNullToken",
+			update:     SliceRemoveOrSet([]string{"null"}),
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name:       "SliceElementContainsSeparator",
+			update:     SliceRemoveOrSet([]string{"a,b"}),
+			errorCheck: expect.ErrorNonNil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, err := testCase.update.MarshalText()
+			testCase.errorCheck(t, err)
+			if err == nil {
+				expect.Equal(t, string(actual), testCase.expected)
+			}
+		})
+	}
+}
+
+func TestUpdate_UnmarshalText(t *testing.T) {
+	t.Run("Int", func(t *testing.T) {
+		testCases := []struct {
+			text       string
+			expected   Update[int]
+			errorCheck expect.ErrorCheck
+		}{
+			{text: "undefined", expected: Noop[int](), errorCheck: expect.ErrorNil},
+			{text: "null", expected: Remove[int](), errorCheck: expect.ErrorNil},
+			{text: "", errorCheck: expect.ErrorIs(strconv.ErrSyntax)},
+			{text: "42", expected: Set(42), errorCheck: expect.ErrorNil},
+			{text: "forty-two", errorCheck: expect.ErrorIs(strconv.ErrSyntax)},
+		}
+		for _, testCase := range testCases {
+			t.Run(testCase.text, func(t *testing.T) {
+				actual := Set(1)
+				err := actual.UnmarshalText([]byte(testCase.text))
+				testCase.errorCheck(t, err)
+				if err == nil {
+					expect.Equal(t, actual, testCase.expected)
+				}
+			})
+		}
+	})
+	t.Run("Int8Overflow", func(t *testing.T) {
+		var actual Update[int8]
+		err := actual.UnmarshalText([]byte("128"))
+		expect.ErrorIs(strconv.ErrRange)(t, err)
+	})
+	t.Run("String", func(t *testing.T) {
+		testCases := []struct {
+			text     string
+			expected Update[string]
+		}{
+			{text: "undefined", expected: Noop[string]()},
+			{text: "null", expected: Remove[string]()},
+			{text: "", expected: Set("")},
+			{text: "[]", expected: Set("[]")},
+		}
+		for _, testCase := range testCases {
+			t.Run(testCase.text, func(t *testing.T) {
+				actual := Set("x")
+				err := actual.UnmarshalText([]byte(testCase.text))
+				expect.ErrorNil(t, err)
+				expect.Equal(t, actual, testCase.expected)
+			})
+		}
+	})
+	t.Run("TextUnmarshaler", func(t *testing.T) {
+		var actual Update[netip.Addr]
+		err := actual.UnmarshalText([]byte("192.0.2.1"))
+		expect.ErrorNil(t, err)
+		expect.Equal(t, actual, Set(netip.MustParseAddr("192.0.2.1")))
+	})
+	t.Run("Slice", func(t *testing.T) {
+		testCases := []struct {
+			text       string
+			expected   SliceUpdate[int]
+			errorCheck expect.ErrorCheck
+		}{
+			{text: "undefined", expected: SliceNoop[int](), errorCheck: expect.ErrorNil},
+			{text: "null", expected: SliceRemove[int](), errorCheck: expect.ErrorNil},
+			{text: "[]", expected: SliceRemoveOrSet([]int{}), errorCheck: expect.ErrorNil},
+			{text: "1,2,3", expected: SliceRemoveOrSet([]int{1, 2, 3}), errorCheck: expect.ErrorNil},
+			{text: "", errorCheck: expect.ErrorIs(strconv.ErrSyntax)},
+			{text: "1,,3", errorCheck: expect.ErrorIs(strconv.ErrSyntax)},
+		}
+		for _, testCase := range testCases {
+			t.Run(testCase.text, func(t *testing.T) {
+				actual := SliceRemoveOrSet([]int{9})
+				err := actual.UnmarshalText([]byte(testCase.text))
+				testCase.errorCheck(t, err)
+				if err == nil {
+					expect.Equal(t, actual, testCase.expected)
+				}
+			})
+		}
+	})
+	t.Run("StringSlice", func(t *testing.T) {
+		var actual SliceUpdate[string]
+		err := actual.UnmarshalText([]byte(""))
+		expect.ErrorNil(t, err)
+		expect.Equal(t, actual, SliceRemoveOrSet([]string{""}))
+	})
+}
+
+func TestTextOptions(t *testing.T) {
+	opts := TextOptions{
+		Null:      "NULL",
+		Noop:      "-",
+		Separator: "|",
+	}
+	testCases := []struct {
+		name   string
+		update interface{}
+		text   string
+	}{
+		{name: "Noop", update: Noop[string](), text: "-"},
+		{name: "Remove", update: Remove[string](), text: "NULL"},
+		{name: "SetEmpty", update: Set(""), text: ""},
+		{name: "SetLowercaseNull", update: Set("null"), text: "null"},
+		{name: "SliceNoop", update: SliceNoop[string](), text: "-"},
+		{name: "SliceRemove", update: SliceRemove[string](), text: "NULL"},
+		{name: "SliceSet", update: SliceRemoveOrSet([]string{"a,b", "c"}), text: "a,b|c"},
+		{name: "SliceSetEmpty", update: SliceRemoveOrSet([]string{}), text: "[]"},
+		{name: "SliceSetEmptyString", update: SliceRemoveOrSet([]string{""}), text: ""},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			text, err := opts.MarshalText(testCase.update)
+			expect.ErrorNil(t, err)
+			expect.Equal(t, string(text), testCase.text)
+
+			// Round trip, via a pointer to a new update of the same type.
+			var actual interface{}
+			switch testCase.update.(type) {
+			case Update[string]:
+				var u Update[string]
+				err = opts.UnmarshalText(text, &u)
+				actual = u
+			case SliceUpdate[string]:
+				var u SliceUpdate[string]
+				err = opts.UnmarshalText(text, &u)
+				actual = u
+			}
+			expect.ErrorNil(t, err)
+			expect.Equal(t, actual, testCase.update)
+		})
+	}
+}
+
+func TestTextOptions_Errors(t *testing.T) {
+	var (
+		update = Set(1)
+		valid  = TextOptions{}
+		same   = TextOptions{Null: "x", Noop: "x"}
+		empty  = TextOptions{Empty: "null"}
+	)
+	t.Run("MarshalNil", func(t *testing.T) {
+		_, err := valid.MarshalText(nil)
+		expect.ErrorNonNil(t, err)
+	})
+	t.Run("MarshalNilPointer", func(t *testing.T) {
+		_, err := valid.MarshalText((*Update[int])(nil))
+		expect.ErrorNonNil(t, err)
+	})
+	t.Run("MarshalNotUpdate", func(t *testing.T) {
+		_, err := valid.MarshalText(1)
+		expect.ErrorNonNil(t, err)
+	})
+	t.Run("MarshalAmbiguousTokens", func(t *testing.T) {
+		_, err := same.MarshalText(update)
+		expect.ErrorNonNil(t, err)
+	})
+	t.Run("MarshalAmbiguousEmpty", func(t *testing.T) {
+		_, err := empty.MarshalText(SliceRemoveOrSet([]int{1}))
+		expect.ErrorNonNil(t, err)
+	})
+	t.Run("UnmarshalNotPointer", func(t *testing.T) {
+		err := valid.UnmarshalText([]byte("1"), update)
+		expect.ErrorNonNil(t, err)
+	})
+	t.Run("UnmarshalNilPointer", func(t *testing.T) {
+		err := valid.UnmarshalText([]byte("1"), (*Update[int])(nil))
+		expect.ErrorNonNil(t, err)
+	})
+	t.Run("UnmarshalAmbiguousTokens", func(t *testing.T) {
+		err := same.UnmarshalText([]byte("1"), &update)
+		expect.ErrorNonNil(t, err)
+	})
+	t.Run("UnmarshalAmbiguousEmpty", func(t *testing.T) {
+		var u SliceUpdate[int]
+		err := empty.UnmarshalText([]byte("1"), &u)
+		expect.ErrorNonNil(t, err)
+	})
+}
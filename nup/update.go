@@ -1,8 +1,12 @@
 package nup
 
 import (
+	"database/sql"
 	"encoding/json"
+	"encoding/xml"
+	"flag"
 	"fmt"
+	"reflect"
 )
 
 // Update represents an update that can be applied to a value field. It may set,
@@ -46,6 +50,32 @@ func RemoveOrSet[T comparable](ptr *T) Update[T] {
 	return Set(*ptr)
 }
 
+// FromNull returns an update corresponding to the given sql.Null value. If
+// present is false, the update is a no-op; otherwise, it removes if the value
+// is invalid (NULL) or sets to the value if it's valid. FromNull is the inverse
+// of ToNull.
+func FromNull[T comparable](value sql.Null[T], present bool) Update[T] {
+	switch {
+	case !present:
+		return Noop[T]()
+	case !value.Valid:
+		return Remove[T]()
+	default:
+		return Set(value.V)
+	}
+}
+
+// FromDoublePtr returns an update corresponding to the given double pointer,
+// following the convention that a nil **T means "no change", a non-nil **T to
+// a nil *T means "remove", and otherwise the **T points to the value to set.
+// FromDoublePtr is the inverse of ToDoublePtr.
+func FromDoublePtr[T comparable](ptr **T) Update[T] {
+	if ptr == nil {
+		return Noop[T]()
+	}
+	return RemoveOrSet(*ptr)
+}
+
 // ValueOperation returns the value this update sets fields to (if any) and the
 // operation this update performs: no-op, remove, or set. If this update is not
 // a set operation, then the returned value is T's zero value; i.e., the value
@@ -106,6 +136,31 @@ func (u Update[T]) ValueOrNil() *T {
 	return &value
 }
 
+// ToNull returns this update as an sql.Null value, which is valid if the update
+// is a set operation, along with whether the update is a change (i.e., not a
+// no-op). ToNull is the inverse of FromNull.
+func (u Update[T]) ToNull() (value sql.Null[T], present bool) {
+	if u.op == OpSet {
+		value = sql.Null[T]{
+			V:     u.value,
+			Valid: true,
+		}
+	}
+	return value, u.op != OpNoop
+}
+
+// ToDoublePtr returns this update as a double pointer: nil if the update is a
+// no-op, a pointer to a nil *T if it's a removal, or a pointer to a pointer to
+// a copy of the update's value if it's a set operation. ToDoublePtr is the
+// inverse of FromDoublePtr.
+func (u Update[T]) ToDoublePtr() **T {
+	if u.op == OpNoop {
+		return nil
+	}
+	value := u.ValueOrNil()
+	return &value
+}
+
 // Apply returns the result of applying the update to the given value. The
 // result is the given value if the update is a no-op, the zero value if it's a
 // removal, or the update's contained value if it's a set operation.
@@ -180,6 +235,204 @@ func (u *Update[T]) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &u.value)
 }
 
+// MarshalXML implements xml.Marshaler. It marshals a set operation as an
+// element containing the value and a removal as an empty element with the
+// attribute xsi:nil="true", declaring the xsi namespace. It writes nothing at
+// all for a no-op, so the omitempty XML struct tag option is unnecessary.
+func (u Update[T]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	switch u.op {
+	case OpNoop:
+		return nil
+	case OpRemove:
+		return encodeNilElement(e, start)
+	default: // Set
+		return e.EncodeElement(u.value, start)
+	}
+}
+
+// UnmarshalXML implements xml.Unmarshaler. It unmarshals an element with the
+// attribute xsi:nil="true" as a removal and any other element as a set
+// operation, while an absent element remains a no-op. The xsi prefix is
+// recognized even if the document doesn't declare it.
+func (u *Update[T]) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	if isNilElement(start) {
+		*u = Remove[T]()
+		return d.Skip()
+	}
+	var value T
+	if err := d.DecodeElement(&value, &start); err != nil {
+		return err
+	}
+	*u = Set(value)
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. Unlike JSON, the binary
+// form distinguishes all three operations wherever the update appears, e.g.
+// in a slice. It consists of the Operation as a single byte followed, for a
+// set operation, by the value: its own binary form if it implements
+// encoding.BinaryMarshaler, or else its encoding/gob encoding.
+func (u Update[T]) MarshalBinary() ([]byte, error) {
+	data := []byte{byte(u.op)}
+	if u.op != OpSet {
+		return data, nil
+	}
+	data, err := appendBinaryValue(data, u.value)
+	if err != nil {
+		return nil, fmt.Errorf("nup: %w", err)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding the binary
+// form written by MarshalBinary.
+func (u *Update[T]) UnmarshalBinary(data []byte) error {
+	op, valueData, err := parseBinaryOperation(data)
+	if err != nil {
+		return err
+	}
+	if op != OpSet {
+		*u = Update[T]{op: op}
+		return nil
+	}
+	var value T
+	if err := parseBinaryValue(valueData, &value); err != nil {
+		return fmt.Errorf("nup: %w", err)
+	}
+	*u = Set(value)
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder, which encoding/gob requires since
+// Update's fields are unexported. It's equivalent to MarshalBinary.
+func (u Update[T]) GobEncode() ([]byte, error) {
+	return u.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder. It's equivalent to UnmarshalBinary.
+func (u *Update[T]) GobDecode(data []byte) error {
+	return u.UnmarshalBinary(data)
+}
+
+// MarshalYAML implements the Marshaler interface of yaml.v2, yaml.v3, and
+// similar libraries. Like MarshalJSON, it marshals a set operation as its value
+// and a removal as null. To omit no-ops, use the omitempty YAML struct tag
+// option, which respects IsZero.
+func (u Update[T]) MarshalYAML() (interface{}, error) {
+	if u.op == OpSet {
+		return u.value, nil
+	}
+	return nil, nil
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of yaml.v2 and similar
+// libraries, which call it with a function that decodes the YAML value into its
+// argument. It unmarshals a value as a set operation, while an absent field
+// remains a no-op.
+//
+// Removals are only partly supported: yaml.v2 and yaml.v3 never call
+// UnmarshalYAML for null (~) values, instead leaving the field zero-valued,
+// i.e. a no-op, so with those libraries null cannot express a removal. Only a
+// library that does call UnmarshalYAML for null gets a removal. To decode null
+// as a removal with yaml.v2 or yaml.v3, convert the YAML to JSON and use
+// json.Unmarshal or Decode.
+func (u *Update[T]) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value *T
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	*u = RemoveOrSet(value)
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler using the default TextOptions.
+// The value of a set operation is marshalled using its own MarshalText method,
+// if it has one, or else strconv, which supports strings, booleans, and
+// numbers.
+func (u Update[T]) MarshalText() ([]byte, error) {
+	return u.marshalText(TextOptions{}.withDefaults())
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using the default
+// TextOptions. Text other than the no-op and null tokens is unmarshalled into
+// the value of a set operation using its own UnmarshalText method, if it has
+// one, or else strconv.
+func (u *Update[T]) UnmarshalText(text []byte) error {
+	return u.unmarshalText(text, TextOptions{}.withDefaults())
+}
+
+// marshalText implements textCodec.
+func (u *Update[T]) marshalText(o TextOptions) ([]byte, error) {
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	if token, ok := o.token(u.op); ok {
+		return []byte(token), nil
+	}
+	text, err := formatText(u.value)
+	if err != nil {
+		return nil, err
+	}
+	return o.marshalValueText(text)
+}
+
+// unmarshalText implements textCodec.
+func (u *Update[T]) unmarshalText(text []byte, o TextOptions) error {
+	if err := o.validate(); err != nil {
+		return err
+	}
+	op := o.operation(text)
+	if op != OpSet {
+		*u = Update[T]{op: op}
+		return nil
+	}
+	var value T
+	if err := parseText(text, &value); err != nil {
+		return err
+	}
+	*u = Set(value)
+	return nil
+}
+
+// decodeValues implements valuesCodec.
+func (u *Update[T]) decodeValues(values []string, null string) error {
+	switch valuesOperation(values, null) {
+	case OpNoop:
+		*u = Noop[T]()
+		return nil
+	case OpRemove:
+		*u = Remove[T]()
+		return nil
+	}
+	if len(values) > 1 {
+		return ErrMultipleValues
+	}
+	var value T
+	if err := parseText([]byte(values[0]), &value); err != nil {
+		return err
+	}
+	*u = Set(value)
+	return nil
+}
+
+// encodeValues implements valuesCodec.
+func (u *Update[T]) encodeValues(null string) ([]string, error) {
+	switch u.op {
+	case OpNoop:
+		return nil, nil
+	case OpRemove:
+		return []string{null}, nil
+	}
+	text, err := formatText(u.value)
+	if err != nil {
+		return nil, err
+	}
+	if string(text) == null {
+		return nil, errNullValue(text, null)
+	}
+	return []string{string(text)}, nil
+}
+
 // IsSetTo returns whether the update sets to the given value.
 func (u Update[T]) IsSetTo(value T) bool {
 	return u.op == OpSet && u.value == value
@@ -227,3 +480,80 @@ func (u Update[T]) interfaceValue() interface{} {
 	}
 	return nil
 }
+
+// applyTo implements updateApplier, which ApplyPatch uses to apply updates to
+// struct fields of type T or *T.
+func (u Update[T]) applyTo(field reflect.Value) bool {
+	switch dst := field.Addr().Interface().(type) {
+	case *T:
+		*dst = u.Apply(*dst)
+	case **T:
+		*dst = u.ApplyPtr(*dst)
+	default:
+		return false
+	}
+	return true
+}
+
+// changes implements updateApplier, which DiffPatch uses to check whether
+// updates would change struct fields of type T or *T.
+func (u Update[T]) changes(field reflect.Value) bool {
+	switch src := field.Interface().(type) {
+	case T:
+		return u.Diff(src).IsChange()
+	case *T:
+		return u.DiffPtr(src).IsChange()
+	default:
+		return false
+	}
+}
+
+// assignFrom implements updateAssigner, which FromFieldMask uses to build
+// updates from struct fields of type T or *T. A zero T or nil *T yields a
+// removal.
+func (u *Update[T]) assignFrom(field reflect.Value) bool {
+	switch src := field.Interface().(type) {
+	case T:
+		var zero T
+		if src == zero {
+			*u = Remove[T]()
+		} else {
+			*u = Set(src)
+		}
+	case *T:
+		*u = RemoveOrSet(src)
+	default:
+		return false
+	}
+	return true
+}
+
+// assignValue implements updateAssigner, which DiffModels uses to build
+// updates from struct fields of type T or *T. Unlike assignFrom, a zero T
+// yields a set operation; only a nil *T yields a removal.
+func (u *Update[T]) assignValue(field reflect.Value) bool {
+	switch src := field.Interface().(type) {
+	case T:
+		*u = Set(src)
+	case *T:
+		*u = RemoveOrSet(src)
+	default:
+		return false
+	}
+	return true
+}
+
+// flagValue implements flagger.
+func (u *Update[T]) flagValue() flag.Value {
+	return Flag(u)
+}
+
+// clearFlagValue implements flagger.
+func (u *Update[T]) clearFlagValue() flag.Value {
+	return ClearFlag(u)
+}
+
+// decodeEnv implements envCodec.
+func (u *Update[T]) decodeEnv(value string, opts EnvOptions) error {
+	return u.decodeValues([]string{value}, opts.Null)
+}
@@ -1,6 +1,7 @@
 package nup
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"testing"
@@ -612,3 +613,96 @@ func TestUpdate_Equal(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdate_NullConversions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		update  Update[int]
+		null    sql.Null[int]
+		present bool
+	}{
+		{
+			name:    "Noop",
+			update:  Noop[int](),
+			null:    sql.Null[int]{},
+			present: false,
+		},
+		{
+			name:    "Remove",
+			update:  Remove[int](),
+			null:    sql.Null[int]{},
+			present: true,
+		},
+		{
+			name:    "SetZero",
+			update:  Set(0),
+			null:    sql.Null[int]{V: 0, Valid: true},
+			present: true,
+		},
+		{
+			name:    "Set",
+			update:  Set(testValue),
+			null:    sql.Null[int]{V: testValue, Valid: true},
+			present: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			null, present := testCase.update.ToNull()
+			expect.Equal(t, null, testCase.null)
+			expect.Equal(t, present, testCase.present)
+			expect.Equal(t, FromNull(null, present), testCase.update)
+		})
+	}
+}
+
+func TestFromNull_NotPresent(t *testing.T) {
+	actual := FromNull(sql.Null[int]{V: testValue, Valid: true}, false)
+	expect.Equal(t, actual, Noop[int]())
+}
+
+func TestUpdate_DoublePtrConversions(t *testing.T) {
+	var (
+		zero     = 0
+		zeroPtr  = &zero
+		value    = testValue
+		valuePtr = &value
+		nilPtr   *int
+	)
+	testCases := []struct {
+		name   string
+		update Update[int]
+		ptr    **int
+	}{
+		{
+			name:   "Noop",
+			update: Noop[int](),
+			ptr:    nil,
+		},
+		{
+			name:   "Remove",
+			update: Remove[int](),
+			ptr:    &nilPtr,
+		},
+		{
+			name:   "SetZero",
+			update: Set(0),
+			ptr:    &zeroPtr,
+		},
+		{
+			name:   "Set",
+			update: Set(testValue),
+			ptr:    &valuePtr,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ptr := testCase.update.ToDoublePtr()
+			expect.Equal(t, ptr, testCase.ptr)
+			expect.Equal(t, FromDoublePtr(ptr), testCase.update)
+			expect.Equal(t, FromDoublePtr(testCase.ptr), testCase.update)
+		})
+	}
+}
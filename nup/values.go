@@ -0,0 +1,171 @@
+package nup
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrMultipleValues indicates a key with more than one value for an Update
+// field, when decoding url.Values.
+var ErrMultipleValues = errors.New("multiple values")
+
+// ValuesOptions configures DecodeValues and EncodeValues.
+type ValuesOptions struct {
+	// Null is the value representing a removal. If it's empty, the default,
+	// a key with an empty value removes the field. Otherwise, only the given
+	// sentinel, e.g. "__null__", removes the field, and an empty value is
+	// unmarshalled like any other, e.g. as the empty string.
+	Null string
+	// DisallowUnknownKeys causes DecodeValues to reject keys that don't
+	// correspond to a field of the patch struct.
+	DisallowUnknownKeys bool
+}
+
+// valuesCodec is implemented by Update and SliceUpdate pointers, so that they
+// can be decoded from and encoded to url.Values.
+type valuesCodec interface {
+	decodeValues(values []string, null string) error
+	encodeValues(null string) ([]string, error)
+}
+
+// DecodeValues decodes URL query parameters or form values into the patch
+// struct pointed to by patch. Each field is identified by its JSON key, and the
+// fields of nested patch structs by their keys joined with dots, e.g.
+// "address.city". A missing key leaves its field a no-op; a key whose only
+// value is the null value (see ValuesOptions.Null) removes it; and otherwise
+// the field is set. The value of an Update[T] is unmarshalled from the key's
+// sole value, like UnmarshalText, while the value of a SliceUpdate[T] is
+// unmarshalled from all of the key's values, in order, e.g. "tag=a&tag=b".
+// Since it has no values, a SliceUpdate can't be set to an empty slice.
+//
+// Like Decode, DecodeValues enforces the constraints expressed by "nup" struct
+// tags, and returns any problems found as a FieldErrors.
+func DecodeValues(values url.Values, patch interface{}, opts ValuesOptions) error {
+	v := reflect.ValueOf(patch)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("nup: DecodeValues requires a non-nil pointer to a struct, not %T", patch)
+	}
+	var (
+		fields = patchFields(patch)
+		known  = make(map[string]bool, len(fields))
+		errs   FieldErrors
+	)
+	for _, field := range fields {
+		known[valuesKey(field.path)] = true
+	}
+	if opts.DisallowUnknownKeys {
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			if !known[key] {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			errs = append(errs, &FieldError{
+				Path: valuesPointer(key),
+				Op:   valuesOperation(values[key], opts.Null),
+				Err:  ErrUnknownKey,
+			})
+		}
+	}
+	for _, field := range fields {
+		fieldValues := values[valuesKey(field.path)]
+		op := valuesOperation(fieldValues, opts.Null)
+		fieldError := func(err error) *FieldError {
+			return &FieldError{
+				Path:  field.path,
+				Field: field.name,
+				Op:    op,
+				Err:   err,
+			}
+		}
+		if err := field.opts.check(op); err != nil {
+			errs = append(errs, fieldError(err))
+			continue
+		}
+		if op == OpNoop {
+			continue
+		}
+		codec := field.value.Addr().Interface().(valuesCodec)
+		if err := codec.decodeValues(fieldValues, opts.Null); err != nil {
+			errs = append(errs, fieldError(err))
+		}
+	}
+	return errs.Err()
+}
+
+// EncodeValues encodes the changes made by patch, a patch struct or a pointer
+// to one, as URL query parameters or form values, in the format understood by
+// DecodeValues. It returns any fields that can't be encoded as a FieldErrors;
+// in particular, a set value whose text is equal to the null value, or a
+// SliceUpdate set to an empty slice, can't be encoded.
+func EncodeValues(patch interface{}, opts ValuesOptions) (url.Values, error) {
+	if _, ok := structValue(patch); !ok {
+		return nil, fmt.Errorf("nup: EncodeValues requires a patch struct, not %T", patch)
+	}
+	var (
+		values = url.Values{}
+		errs   FieldErrors
+	)
+	for _, field := range patchFields(patch) {
+		// Copy the field, which may not be addressable, to call the pointer
+		// method.
+		update := reflect.New(field.Type)
+		update.Elem().Set(field.value)
+		fieldValues, err := update.Interface().(valuesCodec).encodeValues(opts.Null)
+		if err != nil {
+			errs = append(errs, &FieldError{
+				Path:  field.path,
+				Field: field.name,
+				Op:    update.Elem().Interface().(updateMarshaller).Operation(),
+				Err:   err,
+			})
+			continue
+		}
+		if fieldValues != nil {
+			values[valuesKey(field.path)] = fieldValues
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return values, nil
+}
+
+// valuesOperation returns the operation represented by the values of a key.
+func valuesOperation(values []string, null string) Operation {
+	switch {
+	case len(values) == 0:
+		return OpNoop
+	case len(values) == 1 && values[0] == null:
+		return OpRemove
+	default:
+		return OpSet
+	}
+}
+
+// valuesKey converts a JSON pointer to a field of a patch struct to the
+// corresponding url.Values key, e.g. "address.city".
+func valuesKey(pointer string) string {
+	return strings.Join(pointerKeys(pointer), ".")
+}
+
+// valuesPointer converts a url.Values key to the corresponding JSON pointer,
+// e.g. "/address/city".
+func valuesPointer(key string) string {
+	var path string
+	for _, part := range strings.Split(key, ".") {
+		path = appendPointer(path, part)
+	}
+	return path
+}
+
+// errNullValue indicates a set value whose text is equal to the null value.
+func errNullValue(text []byte, null string) error {
+	return fmt.Errorf("cannot encode set value %q, which is the null value %q", text, null)
+}
@@ -0,0 +1,258 @@
+package nup
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/nicheinc/expect"
+)
+
+type testFormPatch struct {
+	Name    Update[string]      `json:"name"`
+	Age     Update[int]         `json:"age"`
+	Tags    SliceUpdate[string] `json:"tags"`
+	Address testAddressPatch    `json:"address"`
+}
+
+func TestDecodeValues(t *testing.T) {
+	testCases := []struct {
+		name       string
+		values     url.Values
+		opts       ValuesOptions
+		expected   testFormPatch
+		errorCheck expect.ErrorCheck
+	}{
+		{
+			name:       "Empty",
+			values:     url.Values{},
+			expected:   testFormPatch{},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "SetAndRemove",
+			values: url.Values{
+				"name":         {"Alice"},
+				"age":          {""},
+				"tags":         {"a", "b"},
+				"address.city": {"Paris"},
+				"address.zip":  {""},
+				"unknown":      {"1"},
+			},
+			expected: testFormPatch{
+				Name:    Set("Alice"),
+				Age:     Remove[int](),
+				Tags:    SliceRemoveOrSet([]string{"a", "b"}),
+				Address: testAddressPatch{City: Set("Paris"), Zip: Remove[string]()},
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "SliceRemove",
+			values: url.Values{
+				"tags": {""},
+			},
+			expected: testFormPatch{
+				Tags: SliceRemove[string](),
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "NoValues",
+			values: url.Values{
+				"name": {},
+			},
+			expected:   testFormPatch{},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "NullSentinel",
+			values: url.Values{
+				"name": {""},
+				"age":  {"__null__"},
+				"tags": {"__null__"},
+			},
+			opts: ValuesOptions{Null: "__null__"},
+			expected: testFormPatch{
+				Name: Set(""),
+				Age:  Remove[int](),
+				Tags: SliceRemove[string](),
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "InvalidValue",
+			values: url.Values{
+				"age": {"old"},
+			},
+			errorCheck: expect.ErrorIs(strconv.ErrSyntax),
+		},
+		{
+			name: "MultipleValues",
+			values: url.Values{
+				"name": {"Alice", "Bob"},
+			},
+			errorCheck: expect.ErrorIs(ErrMultipleValues),
+		},
+		{
+			name: "UnknownKeyDisallowed",
+			values: url.Values{
+				"unknown": {"1"},
+			},
+			opts:       ValuesOptions{DisallowUnknownKeys: true},
+			errorCheck: expect.ErrorIs(ErrUnknownKey),
+		},
+		{
+			name: "NonnullNested",
+			values: url.Values{
+				"address.city": {""},
+			},
+			errorCheck: expect.ErrorIs(ErrNull),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var actual testFormPatch
+			err := DecodeValues(testCase.values, &actual, testCase.opts)
+			testCase.errorCheck(t, err)
+			if err == nil {
+				expect.Equal(t, actual, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestDecodeValues_FieldErrors(t *testing.T) {
+	values := url.Values{
+		"id":    {"1"},
+		"tags":  {""},
+		"age":   {"old"},
+		"extra": {""},
+	}
+	var patch testPatch
+	err := DecodeValues(values, &patch, ValuesOptions{DisallowUnknownKeys: true})
+	var fieldErrors FieldErrors
+	if !errors.As(err, &fieldErrors) {
+		t.Fatalf("Expected FieldErrors, got %T", err)
+	}
+	actual := make([]FieldError, len(fieldErrors))
+	for i, fieldError := range fieldErrors {
+		actual[i] = *fieldError
+	}
+	expected := []FieldError{
+		{Path: "/extra", Op: OpRemove, Err: ErrUnknownKey},
+		{Path: "/id", Field: "ID", Op: OpSet, Err: ErrImmutable},
+		{Path: "/name", Field: "Name", Op: OpNoop, Err: ErrRequired},
+		{Path: "/age", Field: "Age", Op: OpSet, Err: actual[3].Err},
+		{Path: "/tags", Field: "Tags", Op: OpRemove, Err: ErrNull},
+	}
+	expect.Equal(t, actual, expected, cmpopts.EquateErrors())
+	expect.ErrorIs(strconv.ErrSyntax)(t, actual[3].Err)
+}
+
+func TestDecodeValues_NonStruct(t *testing.T) {
+	var patch testFormPatch
+	err := DecodeValues(url.Values{}, patch, ValuesOptions{})
+	expect.ErrorNonNil(t, err)
+}
+
+func TestEncodeValues(t *testing.T) {
+	testCases := []struct {
+		name       string
+		patch      interface{}
+		opts       ValuesOptions
+		expected   url.Values
+		errorCheck expect.ErrorCheck
+	}{
+		{
+			name:       "Noop",
+			patch:      testFormPatch{},
+			expected:   url.Values{},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "SetAndRemove",
+			patch: &testFormPatch{
+				Name:    Set("Alice"),
+				Age:     Remove[int](),
+				Tags:    SliceRemoveOrSet([]string{"a", "b"}),
+				Address: testAddressPatch{Zip: Set("12345")},
+			},
+			expected: url.Values{
+				"name":        {"Alice"},
+				"age":         {""},
+				"tags":        {"a", "b"},
+				"address.zip": {"12345"},
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "NullSentinel",
+			patch: testFormPatch{
+				Name: Set(""),
+				Tags: SliceRemove[string](),
+			},
+			opts: ValuesOptions{Null: "__null__"},
+			expected: url.Values{
+				"name": {""},
+				"tags": {"__null__"},
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "SetNullValue",
+			patch: testFormPatch{
+				Name: Set(""),
+			},
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name: "SliceSetNullValue",
+			patch: testFormPatch{
+				Tags: SliceRemoveOrSet([]string{""}),
+			},
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name: "SliceSetEmpty",
+			patch: testFormPatch{
+				Tags: SliceRemoveOrSet([]string{}),
+			},
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name:       "NotStruct",
+			patch:      5,
+			errorCheck: expect.ErrorNonNil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, err := EncodeValues(testCase.patch, testCase.opts)
+			testCase.errorCheck(t, err)
+			if err == nil {
+				expect.Equal(t, actual, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestEncodeValues_RoundTrip(t *testing.T) {
+	patch := testFormPatch{
+		Name:    Set("Alice"),
+		Age:     Remove[int](),
+		Tags:    SliceRemoveOrSet([]string{"a", "b"}),
+		Address: testAddressPatch{City: Set("Paris")},
+	}
+	values, err := EncodeValues(patch, ValuesOptions{})
+	expect.ErrorNil(t, err)
+
+	var actual testFormPatch
+	err = DecodeValues(values, &actual, ValuesOptions{})
+	expect.ErrorNil(t, err)
+	expect.Equal(t, actual, patch)
+}
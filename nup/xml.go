@@ -0,0 +1,35 @@
+package nup
+
+import (
+	"encoding/xml"
+)
+
+// xsiNamespace is the XML Schema instance namespace, which defines the nil
+// attribute.
+const xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+// encodeNilElement writes an empty element with the attribute xsi:nil="true".
+func encodeNilElement(e *xml.Encoder, start xml.StartElement) error {
+	// encoding/xml would invent its own prefix for an attribute in the xsi
+	// namespace, so declare and use the conventional prefix explicitly.
+	start.Attr = append(start.Attr,
+		xml.Attr{Name: xml.Name{Local: "xmlns:xsi"}, Value: xsiNamespace},
+		xml.Attr{Name: xml.Name{Local: "xsi:nil"}, Value: "true"},
+	)
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// isNilElement returns whether start has the attribute xsi:nil="true" (or
+// "1"), where xsi is either a prefix bound to the XML Schema instance namespace
+// or, if the document doesn't declare it, the literal prefix.
+func isNilElement(start xml.StartElement) bool {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "nil" && (attr.Name.Space == xsiNamespace || attr.Name.Space == "xsi") {
+			return attr.Value == "true" || attr.Value == "1"
+		}
+	}
+	return false
+}
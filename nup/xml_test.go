@@ -0,0 +1,174 @@
+package nup
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/nicheinc/expect"
+)
+
+// Ensure implementation of the xml interfaces.
+var (
+	_ xml.Marshaler   = Update[int]{}
+	_ xml.Unmarshaler = &Update[int]{}
+	_ xml.Marshaler   = SliceUpdate[int]{}
+	_ xml.Unmarshaler = &SliceUpdate[int]{}
+)
+
+type testXMLPatch struct {
+	XMLName xml.Name            `xml:"patch"`
+	Name    Update[string]      `xml:"name,omitempty"`
+	Age     Update[int]         `xml:"age"`
+	Tags    SliceUpdate[string] `xml:"tag"`
+}
+
+func TestUpdate_MarshalXML(t *testing.T) {
+	testCases := []struct {
+		name     string
+		patch    testXMLPatch
+		expected string
+	}{
+		{
+			name:     "Noop",
+			patch:    testXMLPatch{},
+			expected: `<patch></patch>`,
+		},
+		{
+			name: "Remove",
+			patch: testXMLPatch{
+				Age:  Remove[int](),
+				Tags: SliceRemove[string](),
+			},
+			expected: `<patch>` +
+				`<age xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:nil="true"></age>` +
+				`<tag xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:nil="true"></tag>` +
+				`</patch>`,
+		},
+		{
+			name: "Set",
+			patch: testXMLPatch{
+				Name: Set("Alice & Bob"),
+				Age:  Set(0),
+				Tags: SliceRemoveOrSet([]string{"a", "b"}),
+			},
+			expected: `<patch><name>Alice &amp; Bob</name><age>0</age><tag>a</tag><tag>b</tag></patch>`,
+		},
+		{
+			name: "SetEmptySlice",
+			patch: testXMLPatch{
+				Tags: SliceRemoveOrSet([]string{}),
+			},
+			expected: `<patch></patch>`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, err := xml.Marshal(testCase.patch)
+			expect.ErrorNil(t, err)
+			expect.Equal(t, string(actual), testCase.expected)
+		})
+	}
+}
+
+func TestUpdate_UnmarshalXML(t *testing.T) {
+	testCases := []struct {
+		name       string
+		xml        string
+		expected   testXMLPatch
+		errorCheck expect.ErrorCheck
+	}{
+		{
+			name:       "Absent",
+			xml:        `<patch></patch>`,
+			expected:   testXMLPatch{},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "Set",
+			xml:  `<patch><name>Alice</name><age>0</age><tag>a</tag><tag>b</tag></patch>`,
+			expected: testXMLPatch{
+				Name: Set("Alice"),
+				Age:  Set(0),
+				Tags: SliceRemoveOrSet([]string{"a", "b"}),
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "SetEmpty",
+			xml:  `<patch><name/><tag/></patch>`,
+			expected: testXMLPatch{
+				Name: Set(""),
+				Tags: SliceRemoveOrSet([]string{""}),
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "RemoveDeclaredNamespace",
+			xml: `<patch xmlns:i="http://www.w3.org/2001/XMLSchema-instance">` +
+				`<age i:nil="true"/><tag i:nil="1"/></patch>`,
+			expected: testXMLPatch{
+				Age:  Remove[int](),
+				Tags: SliceRemove[string](),
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "RemoveUndeclaredPrefix",
+			xml:  `<patch><name xsi:nil="true"/></patch>`,
+			expected: testXMLPatch{
+				Name: Remove[string](),
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "NilFalse",
+			xml:  `<patch><age xsi:nil="false">1</age></patch>`,
+			expected: testXMLPatch{
+				Age: Set(1),
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "OtherNamespace",
+			xml:  `<patch xmlns:x="urn:other"><name x:nil="true">Alice</name></patch>`,
+			expected: testXMLPatch{
+				Name: Set("Alice"),
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "InvalidValue",
+			xml:        `<patch><age>old</age></patch>`,
+			errorCheck: expect.ErrorNonNil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var actual testXMLPatch
+			err := xml.Unmarshal([]byte(testCase.xml), &actual)
+			testCase.errorCheck(t, err)
+			if err == nil {
+				expect.Equal(t, actual, testCase.expected, cmpopts.IgnoreFields(testXMLPatch{}, "XMLName"))
+			}
+		})
+	}
+}
+
+func TestUpdate_XMLRoundTrip(t *testing.T) {
+	patch := testXMLPatch{
+		XMLName: xml.Name{Local: "patch"},
+		Name:    Remove[string](),
+		Age:     Set(30),
+		Tags:    SliceRemoveOrSet([]string{"a", "b"}),
+	}
+	data, err := xml.Marshal(patch)
+	expect.ErrorNil(t, err)
+
+	var actual testXMLPatch
+	err = xml.Unmarshal(data, &actual)
+	expect.ErrorNil(t, err)
+	expect.Equal(t, actual, patch)
+}
@@ -0,0 +1,284 @@
+package nup
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nicheinc/expect"
+)
+
+// The following interfaces match those of yaml.v2, which nup implements without
+// importing it.
+type (
+	yamlMarshaler interface {
+		MarshalYAML() (interface{}, error)
+	}
+	yamlUnmarshaler interface {
+		UnmarshalYAML(unmarshal func(interface{}) error) error
+	}
+)
+
+// Ensure implementation of the YAML interfaces.
+var (
+	_ yamlMarshaler   = Update[int]{}
+	_ yamlUnmarshaler = &Update[int]{}
+	_ yamlMarshaler   = SliceUpdate[int]{}
+	_ yamlUnmarshaler = &SliceUpdate[int]{}
+)
+
+// yamlStub is a minimal stand-in for a YAML library, supporting flat documents
+// of "key: value" lines, where each value is null (~), an integer, a string,
+// or a flow sequence of those, e.g. "[1, 2]". It calls the YAML interfaces the
+// way yaml.v2 and yaml.v3 do, including not calling UnmarshalYAML for null
+// values, which instead leave the field zero-valued.
+type yamlStub struct{}
+
+func (s yamlStub) Unmarshal(text string, out interface{}) error {
+	doc := map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return fmt.Errorf("invalid line %q", line)
+		}
+		doc[strings.TrimSpace(key)] = parseYAMLStubScalar(strings.TrimSpace(value))
+	}
+	v := reflect.ValueOf(out).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ",")
+		node, ok := doc[key]
+		if !ok {
+			continue
+		}
+		field := v.Field(i)
+		if node == nil {
+			field.SetZero()
+			continue
+		}
+		if unmarshaler, ok := field.Addr().Interface().(yamlUnmarshaler); ok {
+			err := unmarshaler.UnmarshalYAML(func(v interface{}) error {
+				return decodeYAMLStubNode(node, reflect.ValueOf(v).Elem())
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err := decodeYAMLStubNode(node, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseYAMLStubScalar(text string) interface{} {
+	switch {
+	case text == "" || text == "~" || text == "null":
+		return nil
+	case strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]"):
+		seq := []interface{}{}
+		if inner := strings.TrimSpace(text[1 : len(text)-1]); inner != "" {
+			for _, item := range strings.Split(inner, ",") {
+				seq = append(seq, parseYAMLStubScalar(strings.TrimSpace(item)))
+			}
+		}
+		return seq
+	}
+	if i, err := strconv.Atoi(text); err == nil {
+		return i
+	}
+	return strings.Trim(text, `"`)
+}
+
+func decodeYAMLStubNode(node interface{}, v reflect.Value) error {
+	if node == nil {
+		v.SetZero()
+		return nil
+	}
+	if v.Kind() == reflect.Pointer {
+		v.Set(reflect.New(v.Type().Elem()))
+		return decodeYAMLStubNode(node, v.Elem())
+	}
+	switch node := node.(type) {
+	case int:
+		if v.Kind() == reflect.Int {
+			v.SetInt(int64(node))
+			return nil
+		}
+	case string:
+		if v.Kind() == reflect.String {
+			v.SetString(node)
+			return nil
+		}
+	case []interface{}:
+		if v.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(v.Type(), len(node), len(node)))
+			for i, item := range node {
+				if err := decodeYAMLStubNode(item, v.Index(i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot unmarshal %v into %s", node, v.Type())
+}
+
+func (s yamlStub) Marshal(in interface{}) (string, error) {
+	var (
+		builder strings.Builder
+		v       = reflect.ValueOf(in)
+	)
+	for i := 0; i < v.NumField(); i++ {
+		key, opts, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ",")
+		value := v.Field(i).Interface()
+		if zeroer, ok := value.(interface{ IsZero() bool }); ok && opts == "omitempty" && zeroer.IsZero() {
+			continue
+		}
+		if marshaler, ok := value.(yamlMarshaler); ok {
+			var err error
+			if value, err = marshaler.MarshalYAML(); err != nil {
+				return "", err
+			}
+		}
+		fmt.Fprintf(&builder, "%s: %s\n", key, formatYAMLStubNode(value))
+	}
+	return builder.String(), nil
+}
+
+func formatYAMLStubNode(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Slice {
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = formatYAMLStubNode(v.Index(i).Interface())
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return fmt.Sprint(value)
+}
+
+type testYAMLPatch struct {
+	Name Update[string]   `yaml:"name,omitempty"`
+	Age  Update[int]      `yaml:"age,omitempty"`
+	Tags SliceUpdate[int] `yaml:"tags,omitempty"`
+}
+
+func TestUpdate_MarshalYAML(t *testing.T) {
+	testCases := []struct {
+		name     string
+		patch    testYAMLPatch
+		expected string
+	}{
+		{
+			name:     "Noop",
+			patch:    testYAMLPatch{},
+			expected: "",
+		},
+		{
+			name: "Remove",
+			patch: testYAMLPatch{
+				Age:  Remove[int](),
+				Tags: SliceRemove[int](),
+			},
+			expected: "age: null\ntags: null\n",
+		},
+		{
+			name: "Set",
+			patch: testYAMLPatch{
+				Name: Set("Alice"),
+				Age:  Set(0),
+				Tags: SliceRemoveOrSet([]int{1, 2}),
+			},
+			expected: "name: Alice\nage: 0\ntags: [1, 2]\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, err := yamlStub{}.Marshal(testCase.patch)
+			expect.ErrorNil(t, err)
+			expect.Equal(t, actual, testCase.expected)
+		})
+	}
+}
+
+func TestUpdate_UnmarshalYAML(t *testing.T) {
+	testCases := []struct {
+		name       string
+		yaml       string
+		expected   testYAMLPatch
+		errorCheck expect.ErrorCheck
+	}{
+		{
+			name: "Set",
+			yaml: "name: Alice\nage: 0\ntags: [1, 2]",
+			expected: testYAMLPatch{
+				Name: Set("Alice"),
+				Age:  Set(0),
+				Tags: SliceRemoveOrSet([]int{1, 2}),
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "SetEmptySlice",
+			yaml: "tags: []",
+			expected: testYAMLPatch{
+				Tags: SliceRemoveOrSet([]int{}),
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			// yaml.v2 and yaml.v3 don't call UnmarshalYAML for null, so it's
+			// decoded as a no-op rather than a removal.
+			name:       "Null",
+			yaml:       "name: ~\nage: null\ntags:",
+			expected:   testYAMLPatch{},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "Absent",
+			yaml: "name: Alice",
+			expected: testYAMLPatch{
+				Name: Set("Alice"),
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name:       "InvalidValue",
+			yaml:       "age: old",
+			errorCheck: expect.ErrorNonNil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var actual testYAMLPatch
+			err := yamlStub{}.Unmarshal(testCase.yaml, &actual)
+			testCase.errorCheck(t, err)
+			if err == nil {
+				expect.Equal(t, actual, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestUpdate_UnmarshalYAML_Null(t *testing.T) {
+	// A library that does call UnmarshalYAML for null decodes it by leaving
+	// the pointer nil, which is unmarshalled as a removal.
+	null := func(interface{}) error { return nil }
+
+	update := Set(1)
+	err := update.UnmarshalYAML(null)
+	expect.ErrorNil(t, err)
+	expect.Equal(t, update, Remove[int]())
+
+	sliceUpdate := SliceRemoveOrSet([]int{1})
+	err = sliceUpdate.UnmarshalYAML(null)
+	expect.ErrorNil(t, err)
+	expect.Equal(t, sliceUpdate, SliceRemove[int]())
+}
@@ -0,0 +1,210 @@
+/*
+Package nupconfig loads configuration in layers. A base configuration struct is
+overlaid with an ordered list of layers, e.g. a file, the environment, and
+command line flags, each of which is decoded into a patch struct: a struct whose
+fields are nup.Update or nup.SliceUpdate values corresponding to the fields of
+the configuration struct. For example:
+
+	type Config struct {
+		Addr    string
+		Workers *int
+		Tags    []string
+	}
+
+	type ConfigPatch struct {
+		Addr    nup.Update[string]      `json:"addr"`
+		Workers nup.Update[int]         `json:"workers"`
+		Tags    nup.SliceUpdate[string] `json:"tags"`
+	}
+
+	config, provenance, err := nupconfig.Load(Config{Addr: ":8080"},
+		nupconfig.File[ConfigPatch]("config.json"),
+		nupconfig.Env[ConfigPatch]("APP", nup.EnvOptions{}),
+		nupconfig.Flags[ConfigPatch](flag.CommandLine, os.Args[1:]),
+	)
+
+Later layers take precedence over earlier ones. A field that a layer leaves as a
+no-op keeps the value given to it by an earlier layer, or by the base
+configuration, while a removal explicitly resets the field to its zero value,
+even if an earlier layer set it. For example, with the layers above, the
+environment variable APP_WORKERS="" (see nup.FromEnv) or the flag
+-workers-clear (see nup.RegisterFlags) would undo a number of workers set in
+config.json.
+
+Load also reports the provenance of the configuration: which layer last changed
+each field.
+*/
+package nupconfig
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/nicheinc/nullable/v2/nup"
+)
+
+// Layer is a named source of configuration, which decodes its changes into a
+// patch struct of type P.
+type Layer[P any] struct {
+	// Name identifies the layer in errors and in a Provenance.
+	Name string
+	// Decode decodes the layer's changes into the patch struct pointed to by
+	// patch, which is initially all no-ops.
+	Decode func(patch *P) error
+}
+
+// Patch returns a layer named name that makes the changes in patch, e.g. to
+// apply defaults or overrides computed by the program itself.
+func Patch[P any](name string, patch P) Layer[P] {
+	return Layer[P]{
+		Name: name,
+		Decode: func(p *P) error {
+			*p = patch
+			return nil
+		},
+	}
+}
+
+// JSON returns a layer named name that decodes data, a JSON object, with
+// nup.Decode. Unknown keys are rejected, since they're likely to be typos.
+func JSON[P any](name string, data []byte) Layer[P] {
+	return Layer[P]{
+		Name: name,
+		Decode: func(patch *P) error {
+			return nup.Decode(data, patch, nup.DecodeOptions{
+				DisallowUnknownKeys: true,
+			})
+		},
+	}
+}
+
+// File returns a layer, named by path, that decodes the JSON file at path like
+// JSON. It's an error if the file doesn't exist; see OptionalFile.
+func File[P any](path string) Layer[P] {
+	return file[P](path, false)
+}
+
+// OptionalFile is like File, except that a missing file makes no changes.
+func OptionalFile[P any](path string) Layer[P] {
+	return file[P](path, true)
+}
+
+func file[P any](path string, optional bool) Layer[P] {
+	return Layer[P]{
+		Name: path,
+		Decode: func(patch *P) error {
+			data, err := os.ReadFile(path)
+			if optional && os.IsNotExist(err) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			return JSON[P](path, data).Decode(patch)
+		},
+	}
+}
+
+// Env returns a layer named "env" that decodes environment variables with the
+// given prefix using opts.FromEnv.
+func Env[P any](prefix string, opts nup.EnvOptions) Layer[P] {
+	return Layer[P]{
+		Name: "env",
+		Decode: func(patch *P) error {
+			return opts.FromEnv(prefix, patch)
+		},
+	}
+}
+
+// Flags returns a layer named "flags" that registers flags for the patch
+// struct with fs, using nup.RegisterFlags, and then parses args with it. Any
+// other flags should be registered with fs before Load is called. Since the
+// flags can only be registered once, the layer fails if fs already defines any
+// of them, e.g. if it's used by a second call to Load.
+func Flags[P any](fs *flag.FlagSet, args []string) Layer[P] {
+	return Layer[P]{
+		Name: "flags",
+		Decode: func(patch *P) error {
+			// Redefining a flag panics, so check for existing flags by
+			// registering them with a throwaway flag set first.
+			probe := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+			if err := nup.RegisterFlags(probe, patch); err != nil {
+				return err
+			}
+			var err error
+			probe.VisitAll(func(f *flag.Flag) {
+				if err == nil && fs.Lookup(f.Name) != nil {
+					err = fmt.Errorf("flag -%s is already defined", f.Name)
+				}
+			})
+			if err != nil {
+				return err
+			}
+			if err := nup.RegisterFlags(fs, patch); err != nil {
+				return err
+			}
+			return fs.Parse(args)
+		},
+	}
+}
+
+// Origin describes the layer that last changed a field of the configuration.
+type Origin struct {
+	// Layer is the name of the layer.
+	Layer string
+	// Operation is the change the layer made: nup.OpSet or nup.OpRemove.
+	Operation nup.Operation
+}
+
+// Provenance maps the JSON pointer of each field of a patch struct, e.g.
+// "/address/city", to the layer that last changed it. Fields that no layer
+// changed, and which therefore have their base values, are absent.
+type Provenance map[string]Origin
+
+// Layer returns the name of the layer that last changed the field with the
+// given JSON pointer, or base if no layer changed it.
+func (p Provenance) Layer(path, base string) string {
+	if origin, ok := p[path]; ok {
+		return origin.Layer
+	}
+	return base
+}
+
+// Load decodes each layer, in order, into a patch struct of type P, squashes
+// the patches together with nup.SquashPatch, so that later layers take
+// precedence, and applies the result with nup.ApplyPatch to a copy of base made
+// with nup.CopyOnWrite, so that base, including any structs it points to, is
+// left unchanged. It returns the resulting configuration, along with its
+// provenance.
+//
+// Load stops at the first layer that fails to decode, returning an error that
+// identifies the layer and wraps the underlying error, e.g. a nup.FieldErrors.
+func Load[C, P any](base C, layers ...Layer[P]) (C, Provenance, error) {
+	var (
+		combined   P
+		provenance = Provenance{}
+	)
+	for _, layer := range layers {
+		var patch P
+		if err := layer.Decode(&patch); err != nil {
+			return base, nil, fmt.Errorf("nupconfig: layer %s: %w", layer.Name, err)
+		}
+		if err := nup.SquashPatch(&combined, &patch); err != nil {
+			return base, nil, fmt.Errorf("nupconfig: %w", err)
+		}
+		for _, field := range nup.Fields(&patch) {
+			if field.Operation != nup.OpNoop {
+				provenance[field.Path] = Origin{
+					Layer:     layer.Name,
+					Operation: field.Operation,
+				}
+			}
+		}
+	}
+	config := nup.CopyOnWrite(base, combined)
+	if err := nup.ApplyPatch(&config, combined); err != nil {
+		return base, nil, fmt.Errorf("nupconfig: %w", err)
+	}
+	return config, provenance, nil
+}
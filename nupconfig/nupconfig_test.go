@@ -0,0 +1,257 @@
+package nupconfig
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nicheinc/expect"
+
+	"github.com/nicheinc/nullable/v2/nup"
+)
+
+type testConfig struct {
+	Addr    string
+	Timeout *int
+	Tags    []string
+	Debug   bool
+}
+
+type testConfigPatch struct {
+	Addr    nup.Update[string]      `json:"addr"`
+	Timeout nup.Update[int]         `json:"timeout"`
+	Tags    nup.SliceUpdate[string] `json:"tags"`
+	Debug   nup.Update[bool]        `json:"debug"`
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestLoad(t *testing.T) {
+	env := map[string]string{
+		"APP_TIMEOUT": "",
+		"APP_TAGS":    "a,b",
+	}
+	lookupEnv := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+	base := testConfig{
+		Addr:  ":8080",
+		Debug: true,
+	}
+
+	testCases := []struct {
+		name               string
+		layers             []Layer[testConfigPatch]
+		expectedConfig     testConfig
+		expectedProvenance Provenance
+	}{
+		{
+			name:               "NoLayers",
+			expectedConfig:     base,
+			expectedProvenance: Provenance{},
+		},
+		{
+			name: "SingleLayer",
+			layers: []Layer[testConfigPatch]{
+				JSON[testConfigPatch]("file", []byte(`{"addr":":9090","timeout":30}`)),
+			},
+			expectedConfig: testConfig{
+				Addr:    ":9090",
+				Timeout: ptr(30),
+				Debug:   true,
+			},
+			expectedProvenance: Provenance{
+				"/addr":    {Layer: "file", Operation: nup.OpSet},
+				"/timeout": {Layer: "file", Operation: nup.OpSet},
+			},
+		},
+		{
+			name: "LaterLayersWin",
+			layers: []Layer[testConfigPatch]{
+				JSON[testConfigPatch]("file", []byte(`{"addr":":9090","timeout":30,"tags":["x"]}`)),
+				Env[testConfigPatch]("APP", nup.EnvOptions{LookupEnv: lookupEnv}),
+				Flags[testConfigPatch](flag.NewFlagSet("test", flag.ContinueOnError), []string{"-addr=:7070", "-debug-clear"}),
+			},
+			expectedConfig: testConfig{
+				Addr: ":7070",
+				Tags: []string{"a", "b"},
+			},
+			expectedProvenance: Provenance{
+				"/addr":    {Layer: "flags", Operation: nup.OpSet},
+				"/timeout": {Layer: "env", Operation: nup.OpRemove},
+				"/tags":    {Layer: "env", Operation: nup.OpSet},
+				"/debug":   {Layer: "flags", Operation: nup.OpRemove},
+			},
+		},
+		{
+			name: "NoopKeepsEarlierValue",
+			layers: []Layer[testConfigPatch]{
+				Patch("defaults", testConfigPatch{Timeout: nup.Set(10)}),
+				JSON[testConfigPatch]("file", []byte(`{"addr":":9090"}`)),
+			},
+			expectedConfig: testConfig{
+				Addr:    ":9090",
+				Timeout: ptr(10),
+				Debug:   true,
+			},
+			expectedProvenance: Provenance{
+				"/addr":    {Layer: "file", Operation: nup.OpSet},
+				"/timeout": {Layer: "defaults", Operation: nup.OpSet},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			config, provenance, err := Load(base, testCase.layers...)
+			expect.ErrorNil(t, err)
+			expect.Equal(t, config, testCase.expectedConfig)
+			expect.Equal(t, provenance, testCase.expectedProvenance)
+		})
+	}
+}
+
+func TestLoad_BaseUnchanged(t *testing.T) {
+	base := testConfig{
+		Timeout: ptr(10),
+		Tags:    []string{"a"},
+	}
+	_, _, err := Load(base, Patch("override", testConfigPatch{
+		Timeout: nup.Set(20),
+		Tags:    nup.SliceRemoveOrSet([]string{"b"}),
+	}))
+	expect.ErrorNil(t, err)
+	expect.Equal(t, *base.Timeout, 10)
+	expect.Equal(t, base.Tags, []string{"a"})
+}
+
+func TestLoad_BaseUnchanged_Nested(t *testing.T) {
+	type dbConfig struct {
+		Host string
+		Port int
+	}
+	type config struct {
+		DB *dbConfig
+	}
+	type configPatch struct {
+		DB struct {
+			Host nup.Update[string] `json:"host"`
+			Port nup.Update[int]    `json:"port"`
+		} `json:"db"`
+	}
+	var (
+		db   = &dbConfig{Host: "old", Port: 5432}
+		base = config{DB: db}
+	)
+	loaded, _, err := Load(base, JSON[configPatch]("file", []byte(`{"db":{"host":"new"}}`)))
+	expect.ErrorNil(t, err)
+	expect.Equal(t, loaded, config{DB: &dbConfig{Host: "new", Port: 5432}})
+	expect.Equal(t, base.DB, db)
+	expect.Equal(t, *db, dbConfig{Host: "old", Port: 5432})
+
+	// A failed load leaves base unchanged too.
+	failed, _, err := Load(base,
+		JSON[configPatch]("file", []byte(`{"db":{"host":"new"}}`)),
+		JSON[configPatch]("bad", []byte(`{"db":{"port":"x"}}`)),
+	)
+	expect.ErrorNonNil(t, err)
+	expect.Equal(t, failed.DB, db)
+	expect.Equal(t, *db, dbConfig{Host: "old", Port: 5432})
+}
+
+func TestLoad_Errors(t *testing.T) {
+	errLayer := errors.New("layer failed")
+	testCases := []struct {
+		name       string
+		layers     []Layer[testConfigPatch]
+		errorCheck expect.ErrorCheck
+	}{
+		{
+			name: "LayerError",
+			layers: []Layer[testConfigPatch]{
+				{Name: "custom", Decode: func(*testConfigPatch) error { return errLayer }},
+			},
+			errorCheck: expect.ErrorIs(errLayer),
+		},
+		{
+			name: "UnknownKey",
+			layers: []Layer[testConfigPatch]{
+				JSON[testConfigPatch]("file", []byte(`{"adr":":9090"}`)),
+			},
+			errorCheck: expect.ErrorIsAll(nup.ErrUnknownKey),
+		},
+		{
+			name: "MissingFile",
+			layers: []Layer[testConfigPatch]{
+				File[testConfigPatch](filepath.Join(t.TempDir(), "missing.json")),
+			},
+			errorCheck: expect.ErrorIs(os.ErrNotExist),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			base := testConfig{Addr: ":8080"}
+			config, provenance, err := Load(base, testCase.layers...)
+			testCase.errorCheck(t, err)
+			expect.Equal(t, config, base)
+			expect.Equal(t, provenance, nil)
+		})
+	}
+}
+
+func TestFlags_Twice(t *testing.T) {
+	var (
+		fs    = flag.NewFlagSet("test", flag.ContinueOnError)
+		layer = Flags[testConfigPatch](fs, []string{"-addr=:7070"})
+	)
+	config, _, err := Load(testConfig{}, layer)
+	expect.ErrorNil(t, err)
+	expect.Equal(t, config.Addr, ":7070")
+
+	// Registering the flags again would panic.
+	_, _, err = Load(testConfig{}, layer)
+	expect.Equal(t, err.Error(), "nupconfig: layer flags: flag -addr is already defined")
+}
+
+func TestLoad_ErrorMessage(t *testing.T) {
+	_, _, err := Load(testConfig{}, Layer[testConfigPatch]{
+		Name:   "custom",
+		Decode: func(*testConfigPatch) error { return errors.New("layer failed") },
+	})
+	expect.Equal(t, err.Error(), "nupconfig: layer custom: layer failed")
+}
+
+func TestFile(t *testing.T) {
+	var (
+		dir     = t.TempDir()
+		path    = filepath.Join(dir, "config.json")
+		missing = filepath.Join(dir, "missing.json")
+	)
+	err := os.WriteFile(path, []byte(`{"addr":":9090","tags":null}`), 0o600)
+	expect.ErrorNil(t, err)
+
+	config, provenance, err := Load(testConfig{Tags: []string{"a"}},
+		File[testConfigPatch](path),
+		OptionalFile[testConfigPatch](missing),
+	)
+	expect.ErrorNil(t, err)
+	expect.Equal(t, config, testConfig{Addr: ":9090"})
+	expect.Equal(t, provenance, Provenance{
+		"/addr": {Layer: path, Operation: nup.OpSet},
+		"/tags": {Layer: path, Operation: nup.OpRemove},
+	})
+}
+
+func TestProvenance_Layer(t *testing.T) {
+	provenance := Provenance{
+		"/addr": {Layer: "flags", Operation: nup.OpSet},
+	}
+	expect.Equal(t, provenance.Layer("/addr", "base"), "flags")
+	expect.Equal(t, provenance.Layer("/timeout", "base"), "base")
+}
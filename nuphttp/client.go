@@ -0,0 +1,66 @@
+package nuphttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/nicheinc/nullable/v2/nup"
+)
+
+// ErrNoChanges indicates that there are no changes to send, as returned by
+// NewPatchRequest.
+var ErrNoChanges = errors.New("nuphttp: no changes")
+
+// NewPatchRequest returns a PATCH request that changes the model at url from
+// before to after, two snapshots of the model, e.g. as fetched from the server
+// and as edited by the client. The request body is a JSON Merge Patch holding
+// only the fields that differ, computed by nup.DiffModels as a patch struct of
+// type P and encoded with nup.CanonicalJSON, which omits no-ops whether or not
+// P's fields are marked with the "omitzero" JSON struct tag option. Since the
+// encoding is canonical, numbers are encoded as double-precision values, so
+// integers beyond ±2^53 may lose precision.
+//
+// The request's If-Match header holds the ETag of before, so that a
+// PatchHandler rejects it if the model has changed since the snapshot was
+// taken; for the ETags to agree, M must marshal to JSON like the server's
+// model.
+//
+// For example:
+//
+//	edited := user
+//	edited.Name = "Bob"
+//	r, err := nuphttp.NewPatchRequest[UserPatch](ctx, url, user, edited)
+//	if errors.Is(err, nuphttp.ErrNoChanges) {
+//		return nil
+//	}
+//
+// NewPatchRequest returns ErrNoChanges, and a nil request, if before and after
+// don't differ in any field of P.
+func NewPatchRequest[P, M any](ctx context.Context, url string, before, after M) (*http.Request, error) {
+	var patch P
+	changed, err := nup.DiffModels(&patch, before, after)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return nil, ErrNoChanges
+	}
+	body, err := nup.CanonicalJSON(patch)
+	if err != nil {
+		return nil, fmt.Errorf("nuphttp: marshalling patch: %w", err)
+	}
+	etag, err := ETag(before)
+	if err != nil {
+		return nil, err
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPatch, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", MergePatchContentType)
+	r.Header.Set("If-Match", etag)
+	return r, nil
+}
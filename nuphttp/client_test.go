@@ -0,0 +1,144 @@
+package nuphttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nicheinc/expect"
+
+	"github.com/nicheinc/nullable/v2/nup"
+)
+
+func TestNewPatchRequest(t *testing.T) {
+	var (
+		age    = 30
+		before = testModel{Name: "Alice", Age: &age, Tags: []string{"a"}, Address: testAddress{City: "Paris"}}
+	)
+	testCases := []struct {
+		name         string
+		after        testModel
+		expectedBody string
+	}{
+		{
+			name:         "SetField",
+			after:        testModel{Name: "Bob", Age: &age, Tags: []string{"a"}, Address: testAddress{City: "Paris"}},
+			expectedBody: `{"name":"Bob"}`,
+		},
+		{
+			name:         "RemoveFields",
+			after:        testModel{Name: "Alice", Address: testAddress{City: "Paris"}},
+			expectedBody: `{"age":null,"tags":null}`,
+		},
+		{
+			name:         "NestedField",
+			after:        testModel{Name: "Alice", Age: &age, Tags: []string{"a", "b"}, Address: testAddress{City: "Lyon", Zip: "69001"}},
+			expectedBody: `{"address":{"city":"Lyon","zip":"69001"},"tags":["a","b"]}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			r, err := NewPatchRequest[testPatch](context.Background(), "https://example.com/users/1", before, testCase.after)
+			expect.ErrorNil(t, err)
+			expect.Equal(t, r.Method, http.MethodPatch)
+			expect.Equal(t, r.URL.String(), "https://example.com/users/1")
+			expect.Equal(t, r.Header.Get("Content-Type"), MergePatchContentType)
+			etag, err := ETag(before)
+			expect.ErrorNil(t, err)
+			expect.Equal(t, r.Header.Get("If-Match"), etag)
+			body, err := io.ReadAll(r.Body)
+			expect.ErrorNil(t, err)
+			expect.Equal(t, string(body), testCase.expectedBody)
+		})
+	}
+}
+
+func TestNewPatchRequest_NoChanges(t *testing.T) {
+	model := testModel{Name: "Alice"}
+	r, err := NewPatchRequest[testPatch](context.Background(), "https://example.com/users/1", model, model)
+	expect.ErrorIs(ErrNoChanges)(t, err)
+	expect.Equal(t, r, nil)
+}
+
+func TestNewPatchRequest_Errors(t *testing.T) {
+	type model struct {
+		Name int
+	}
+	_, err := NewPatchRequest[testPatch](context.Background(), "https://example.com/users/1", model{}, model{Name: 1})
+	expect.ErrorNonNil(t, err)
+
+	_, err = NewPatchRequest[testPatch](context.Background(), "://invalid", testModel{}, testModel{Name: "Bob"})
+	expect.ErrorNonNil(t, err)
+}
+
+// TestNewPatchRequest_PatchHandler ensures that a PatchHandler applies the
+// request to the model it was computed from, and rejects it once the model has
+// changed.
+func TestNewPatchRequest_PatchHandler(t *testing.T) {
+	var (
+		age    = 30
+		before = testModel{Name: "Alice", Age: &age, Tags: []string{"a"}}
+		after  = testModel{Name: "Bob", Tags: []string{"a", "b"}, Address: testAddress{City: "Paris"}}
+		store  = &testStore{model: before}
+	)
+	handler := store.handler(true)
+
+	r, err := NewPatchRequest[testPatch](context.Background(), "/users/1", before, after)
+	expect.ErrorNil(t, err)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	expect.Equal(t, w.Code, http.StatusOK)
+	expect.Equal(t, store.model, after)
+
+	// A second request based on the same, now stale, snapshot is rejected.
+	r, err = NewPatchRequest[testPatch](context.Background(), "/users/1", before, testModel{Name: "Carol"})
+	expect.ErrorNil(t, err)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	expect.Equal(t, w.Code, http.StatusPreconditionFailed)
+	expect.Equal(t, store.model, after)
+}
+
+// TestNewPatchRequest_NoOmitzero ensures that no-ops are omitted from the body
+// even if the patch struct's fields aren't marked "omitzero", so that they
+// aren't sent as removals.
+func TestNewPatchRequest_NoOmitzero(t *testing.T) {
+	type userPatch struct {
+		Name  nup.Update[string] `json:"name"`
+		Email nup.Update[string] `json:"email"`
+	}
+	type user struct {
+		Name  string  `json:"name"`
+		Email *string `json:"email"`
+	}
+	var (
+		email  = "a@example.com"
+		before = user{Name: "a", Email: &email}
+		after  = user{Name: "b", Email: &email}
+	)
+	r, err := NewPatchRequest[userPatch](context.Background(), "/users/1", before, after)
+	expect.ErrorNil(t, err)
+	body, err := io.ReadAll(r.Body)
+	expect.ErrorNil(t, err)
+	expect.Equal(t, string(body), `{"name":"b"}`)
+}
+
+// TestNewPatchRequest_ZeroValues ensures that a field edited to its zero value
+// is sent as a set operation, which a PatchHandler accepts even for a nonnull
+// field, rather than as a removal.
+func TestNewPatchRequest_ZeroValues(t *testing.T) {
+	var (
+		before = testModel{Name: "Alice", Address: testAddress{City: "Paris"}}
+		after  = testModel{Name: "", Address: testAddress{City: ""}}
+		store  = &testStore{model: before}
+	)
+	r, err := NewPatchRequest[testPatch](context.Background(), "/users/1", before, after)
+	expect.ErrorNil(t, err)
+	w := httptest.NewRecorder()
+	store.handler(true).ServeHTTP(w, r)
+	expect.Equal(t, w.Code, http.StatusOK)
+	expect.Equal(t, store.model, after)
+}
@@ -0,0 +1,145 @@
+package nuphttp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/nicheinc/nullable/v2/nup"
+)
+
+// The media types accepted by DecodePatch.
+const (
+	JSONContentType       = "application/json"
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// DefaultMaxBodyBytes is the default limit on the size of a request body
+// decoded by DecodePatch.
+const DefaultMaxBodyBytes = 1 << 20
+
+// AcceptPatch is the value of the Accept-Patch response header (RFC 5789)
+// listing the media types accepted by DecodePatch.
+var AcceptPatch = strings.Join([]string{
+	JSONContentType,
+	MergePatchContentType,
+	JSONPatchContentType,
+}, ", ")
+
+// Decoder configures DecodePatch.
+type Decoder struct {
+	// MaxBodyBytes limits the size of the request body. If it's zero, the
+	// default, DefaultMaxBodyBytes is used.
+	MaxBodyBytes int64
+	// Options configures the decoding of the body with nup.Decode.
+	Options nup.DecodeOptions
+}
+
+// DecodePatch decodes the body of r into the patch struct pointed to by patch
+// using the default Decoder.
+func DecodePatch(r *http.Request, patch interface{}) error {
+	return Decoder{}.DecodePatch(r, patch)
+}
+
+// DecodePatch decodes the body of r into the patch struct pointed to by patch,
+// according to its Content-Type header:
+//   - application/json and application/merge-patch+json: the body is a JSON
+//     object decoded with nup.Decode, in which null removes a field, and a
+//     missing key leaves it unchanged, as in a JSON Merge Patch.
+//   - application/json-patch+json: the body is a JSON Patch, an array of
+//     operations, each of which must be an "add" or "replace" operation, which
+//     sets the field at its "path", or a "remove" operation, which removes it.
+//     Operations on elements of slices, e.g. "/tags/0" or "/tags/-", and other
+//     operations, aren't supported, since a patch struct can't represent them.
+//
+// If the body can't be decoded, DecodePatch returns a *Problem with status 413
+// (Content Too Large) if the body exceeds d.MaxBodyBytes, 415 (Unsupported
+// Media Type) if the content type isn't supported, 400 (Bad Request) if the
+// body is malformed, or 422 (Unprocessable Content), listing the offending
+// fields, if the body doesn't fit the patch struct or violates the constraints
+// of its "nup" struct tags. It returns any other error, e.g. if patch isn't a
+// pointer to a struct or the body can't be read, as is.
+func (d Decoder) DecodePatch(r *http.Request, patch interface{}) error {
+	v := reflect.ValueOf(patch)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("nuphttp: DecodePatch requires a non-nil pointer to a struct, not %T", patch)
+	}
+	mediaType, err := requestMediaType(r)
+	if err != nil {
+		return err
+	}
+	body, err := d.readBody(r)
+	if err != nil {
+		return err
+	}
+	if mediaType == JSONPatchContentType {
+		if body, err = mergePatch(body, patch, d.Options); err != nil {
+			return newProblem(http.StatusBadRequest, "Invalid JSON Patch: "+err.Error())
+		}
+	}
+	err = nup.Decode(body, patch, d.Options)
+	var fieldErrors nup.FieldErrors
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &fieldErrors):
+		return fieldProblem(fieldErrors)
+	default:
+		return newProblem(http.StatusBadRequest, "Invalid request body: "+strings.TrimPrefix(err.Error(), "nup: "))
+	}
+}
+
+// requestMediaType returns the media type of the body of r, or a Problem if
+// it's not supported.
+func requestMediaType(r *http.Request) (string, error) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return "", newProblem(http.StatusUnsupportedMediaType, "The Content-Type header is missing.")
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", newProblem(http.StatusUnsupportedMediaType, fmt.Sprintf("The Content-Type header %q is malformed.", contentType))
+	}
+	switch mediaType {
+	case JSONContentType, MergePatchContentType, JSONPatchContentType:
+	default:
+		return "", newProblem(http.StatusUnsupportedMediaType, fmt.Sprintf("The media type %q is not supported; use one of %s.", mediaType, AcceptPatch))
+	}
+	if charset, ok := params["charset"]; ok && !strings.EqualFold(charset, "utf-8") {
+		return "", newProblem(http.StatusUnsupportedMediaType, fmt.Sprintf("The charset %q is not supported; use utf-8.", charset))
+	}
+	return mediaType, nil
+}
+
+// readBody reads the body of r, returning a Problem if it exceeds the size
+// limit.
+func (d Decoder) readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, newProblem(http.StatusBadRequest, "The request body is empty.")
+	}
+	limit := d.MaxBodyBytes
+	if limit == 0 {
+		limit = DefaultMaxBodyBytes
+	}
+	tooLarge := newProblem(http.StatusRequestEntityTooLarge, fmt.Sprintf("The request body exceeds %d bytes.", limit))
+	if r.ContentLength > limit {
+		return nil, tooLarge
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return nil, tooLarge
+		}
+		return nil, fmt.Errorf("nuphttp: reading request body: %w", err)
+	}
+	if int64(len(body)) > limit {
+		return nil, tooLarge
+	}
+	return body, nil
+}
@@ -0,0 +1,309 @@
+package nuphttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nicheinc/expect"
+
+	"github.com/nicheinc/nullable/v2/nup"
+)
+
+type testAddressPatch struct {
+	City nup.Update[string] `json:"city,omitzero"`
+	Zip  nup.Update[string] `json:"zip,omitzero"`
+}
+
+type testPatch struct {
+	Name    nup.Update[string]      `json:"name,omitzero" nup:"nonnull"`
+	Age     nup.Update[int]         `json:"age,omitzero"`
+	Tags    nup.SliceUpdate[string] `json:"tags,omitzero"`
+	Address testAddressPatch        `json:"address,omitzero"`
+}
+
+func newPatchRequest(contentType, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPatch, "/users/1", strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	return r
+}
+
+func TestDecodePatch(t *testing.T) {
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		expected    testPatch
+	}{
+		{
+			name:        "JSON",
+			contentType: "application/json",
+			body:        `{"name":"Alice","age":null,"address":{"city":"Paris"}}`,
+			expected: testPatch{
+				Name:    nup.Set("Alice"),
+				Age:     nup.Remove[int](),
+				Address: testAddressPatch{City: nup.Set("Paris")},
+			},
+		},
+		{
+			name:        "JSONCharset",
+			contentType: "application/json; charset=UTF-8",
+			body:        `{"tags":["a"]}`,
+			expected: testPatch{
+				Tags: nup.SliceRemoveOrSet([]string{"a"}),
+			},
+		},
+		{
+			name:        "MergePatch",
+			contentType: "application/merge-patch+json",
+			body:        `{"tags":null,"address":{"zip":null}}`,
+			expected: testPatch{
+				Tags:    nup.SliceRemove[string](),
+				Address: testAddressPatch{Zip: nup.Remove[string]()},
+			},
+		},
+		{
+			name:        "JSONPatch",
+			contentType: "application/json-patch+json",
+			body: `[
+				{"op":"add","path":"/name","value":"Alice"},
+				{"op":"remove","path":"/age"},
+				{"op":"replace","path":"/address/city","value":"Paris"},
+				{"op":"replace","path":"/tags","value":["a","b"]}
+			]`,
+			expected: testPatch{
+				Name:    nup.Set("Alice"),
+				Age:     nup.Remove[int](),
+				Tags:    nup.SliceRemoveOrSet([]string{"a", "b"}),
+				Address: testAddressPatch{City: nup.Set("Paris")},
+			},
+		},
+		{
+			name:        "JSONPatchLaterOperationsWin",
+			contentType: "application/json-patch+json",
+			body: `[
+				{"op":"add","path":"/address","value":{"city":"Paris","zip":"75001"}},
+				{"op":"remove","path":"/address/zip"},
+				{"op":"add","path":"/age","value":30},
+				{"op":"replace","path":"/age","value":31}
+			]`,
+			expected: testPatch{
+				Age:     nup.Set(31),
+				Address: testAddressPatch{City: nup.Set("Paris"), Zip: nup.Remove[string]()},
+			},
+		},
+		{
+			name:        "JSONPatchEmpty",
+			contentType: "application/json-patch+json",
+			body:        `[]`,
+			expected:    testPatch{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var patch testPatch
+			err := DecodePatch(newPatchRequest(testCase.contentType, testCase.body), &patch)
+			expect.ErrorNil(t, err)
+			expect.Equal(t, patch, testCase.expected)
+		})
+	}
+}
+
+func TestDecodePatch_Problems(t *testing.T) {
+	testCases := []struct {
+		name        string
+		decoder     Decoder
+		contentType string
+		body        string
+		expected    *Problem
+	}{
+		{
+			name:     "MissingContentType",
+			body:     `{}`,
+			expected: newProblem(http.StatusUnsupportedMediaType, "The Content-Type header is missing."),
+		},
+		{
+			name:        "UnsupportedMediaType",
+			contentType: "text/plain",
+			body:        `{}`,
+			expected:    newProblem(http.StatusUnsupportedMediaType, `The media type "text/plain" is not supported; use one of application/json, application/merge-patch+json, application/json-patch+json.`),
+		},
+		{
+			name:        "UnsupportedCharset",
+			contentType: "application/json; charset=latin1",
+			body:        `{}`,
+			expected:    newProblem(http.StatusUnsupportedMediaType, `The charset "latin1" is not supported; use utf-8.`),
+		},
+		{
+			name:        "TooLarge",
+			decoder:     Decoder{MaxBodyBytes: 8},
+			contentType: "application/json",
+			body:        `{"name":"Alice"}`,
+			expected:    newProblem(http.StatusRequestEntityTooLarge, "The request body exceeds 8 bytes."),
+		},
+		{
+			name:        "Malformed",
+			contentType: "application/json",
+			body:        `{"name":`,
+			expected:    newProblem(http.StatusBadRequest, "Invalid request body: unexpected EOF"),
+		},
+		{
+			name:        "InvalidFields",
+			contentType: "application/merge-patch+json",
+			body:        `{"name":null,"age":"old","address":{"city":1}}`,
+			expected: &Problem{
+				Title:  "Unprocessable Entity",
+				Status: http.StatusUnprocessableEntity,
+				Detail: "The request body has invalid fields.",
+				Errors: []FieldProblem{
+					{Pointer: "/name", Detail: "cannot remove: null not allowed"},
+					{Pointer: "/age", Detail: "cannot set: json: cannot unmarshal string into Go value of type int"},
+					{Pointer: "/address/city", Detail: "cannot set: json: cannot unmarshal number into Go value of type string"},
+				},
+			},
+		},
+		{
+			name:        "UnknownKey",
+			decoder:     Decoder{Options: nup.DecodeOptions{DisallowUnknownKeys: true}},
+			contentType: "application/json",
+			body:        `{"nmae":"Alice"}`,
+			expected: &Problem{
+				Title:  "Unprocessable Entity",
+				Status: http.StatusUnprocessableEntity,
+				Detail: "The request body has invalid fields.",
+				Errors: []FieldProblem{
+					{Pointer: "/nmae", Detail: "cannot set: unknown key"},
+				},
+			},
+		},
+		{
+			name:        "JSONPatchNotArray",
+			contentType: "application/json-patch+json",
+			body:        `{"name":"Alice"}`,
+			expected:    newProblem(http.StatusBadRequest, "Invalid JSON Patch: json: cannot unmarshal object into Go value of type []nuphttp.jsonPatchOperation"),
+		},
+		{
+			name:        "JSONPatchNull",
+			contentType: "application/json-patch+json",
+			body:        `null`,
+			expected:    newProblem(http.StatusBadRequest, "Invalid JSON Patch: document must be an array, not null"),
+		},
+		{
+			name:        "JSONPatchUnsupportedOperation",
+			contentType: "application/json-patch+json",
+			body:        `[{"op":"remove","path":"/age"},{"op":"move","from":"/name","path":"/age"}]`,
+			expected:    newProblem(http.StatusBadRequest, `Invalid JSON Patch: operation 1: "move" operations are not supported`),
+		},
+		{
+			name:        "JSONPatchMissingValue",
+			contentType: "application/json-patch+json",
+			body:        `[{"op":"add","path":"/age"}]`,
+			expected:    newProblem(http.StatusBadRequest, `Invalid JSON Patch: operation 0: "add" operation is missing "value"`),
+		},
+		{
+			name:        "JSONPatchMissingOp",
+			contentType: "application/json-patch+json",
+			body:        `[{"path":"/age"}]`,
+			expected:    newProblem(http.StatusBadRequest, `Invalid JSON Patch: operation 0: operation is missing "op"`),
+		},
+		{
+			name:        "JSONPatchMissingPath",
+			contentType: "application/json-patch+json",
+			body:        `[{"op":"remove"}]`,
+			expected:    newProblem(http.StatusBadRequest, `Invalid JSON Patch: operation 0: operation is missing "path"`),
+		},
+		{
+			name:        "JSONPatchWholeDocument",
+			contentType: "application/json-patch+json",
+			body:        `[{"op":"replace","path":"","value":{}}]`,
+			expected:    newProblem(http.StatusBadRequest, "Invalid JSON Patch: operation 0: operations on the whole document are not supported"),
+		},
+		{
+			name:        "JSONPatchInvalidPointer",
+			contentType: "application/json-patch+json",
+			body:        `[{"op":"remove","path":"age"}]`,
+			expected:    newProblem(http.StatusBadRequest, `Invalid JSON Patch: operation 0: path "age" is not a JSON pointer`),
+		},
+		{
+			name:        "JSONPatchArrayIndex",
+			contentType: "application/json-patch+json",
+			body:        `[{"op":"replace","path":"/tags/0","value":"a"}]`,
+			expected:    newProblem(http.StatusBadRequest, `Invalid JSON Patch: operation 0: path "/tags/0" addresses an element or member of /tags, which is not supported`),
+		},
+		{
+			name:        "JSONPatchArrayAppend",
+			contentType: "application/json-patch+json",
+			body:        `[{"op":"add","path":"/tags/-","value":"a"}]`,
+			expected:    newProblem(http.StatusBadRequest, `Invalid JSON Patch: operation 0: path "/tags/-" addresses an element or member of /tags, which is not supported`),
+		},
+		{
+			name:        "JSONPatchUnknownArrayAppend",
+			contentType: "application/json-patch+json",
+			body:        `[{"op":"add","path":"/aliases/-","value":"a"}]`,
+			expected:    newProblem(http.StatusBadRequest, `Invalid JSON Patch: operation 0: path "/aliases/-" addresses an array element, which is not supported`),
+		},
+		{
+			name:        "JSONPatchDuplicatePath",
+			decoder:     Decoder{Options: nup.DecodeOptions{DisallowDuplicateKeys: true}},
+			contentType: "application/json-patch+json",
+			body:        `[{"op":"add","path":"/age","value":1},{"op":"replace","path":"/age","value":2}]`,
+			expected:    newProblem(http.StatusBadRequest, `Invalid JSON Patch: operation 1: path "/age" is changed by more than one operation`),
+		},
+		{
+			name:        "JSONPatchDuplicateKeyInModifiedObject",
+			decoder:     Decoder{Options: nup.DecodeOptions{DisallowDuplicateKeys: true}},
+			contentType: "application/json-patch+json",
+			body:        `[{"op":"add","path":"/address","value":{"city":"A","city":"B"}},{"op":"remove","path":"/address/zip"}]`,
+			expected:    newProblem(http.StatusBadRequest, `Invalid JSON Patch: operation 1: value at "/address": duplicate key "city"`),
+		},
+		{
+			name:        "JSONPatchDuplicateKeyInValue",
+			decoder:     Decoder{Options: nup.DecodeOptions{DisallowDuplicateKeys: true}},
+			contentType: "application/json-patch+json",
+			body:        `[{"op":"add","path":"/address","value":{"city":"A","city":"B"}}]`,
+			expected: &Problem{
+				Title:  "Unprocessable Entity",
+				Status: http.StatusUnprocessableEntity,
+				Detail: "The request body has invalid fields.",
+				Errors: []FieldProblem{
+					{Pointer: "/address/city", Detail: "cannot set: duplicate key"},
+				},
+			},
+		},
+		{
+			name:        "JSONPatchFieldError",
+			contentType: "application/json-patch+json",
+			body:        `[{"op":"remove","path":"/name"}]`,
+			expected: &Problem{
+				Title:  "Unprocessable Entity",
+				Status: http.StatusUnprocessableEntity,
+				Detail: "The request body has invalid fields.",
+				Errors: []FieldProblem{
+					{Pointer: "/name", Detail: "cannot remove: null not allowed"},
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var patch testPatch
+			err := testCase.decoder.DecodePatch(newPatchRequest(testCase.contentType, testCase.body), &patch)
+			var problem *Problem
+			expect.Equal(t, errors.As(err, &problem), true)
+			expect.Equal(t, problem, testCase.expected)
+		})
+	}
+}
+
+func TestDecodePatch_NotPointer(t *testing.T) {
+	err := DecodePatch(newPatchRequest("application/json", `{}`), testPatch{})
+	var problem *Problem
+	expect.ErrorNonNil(t, err)
+	expect.Equal(t, errors.As(err, &problem), false)
+}
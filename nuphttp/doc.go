@@ -0,0 +1,29 @@
+/*
+Package nuphttp provides HTTP helpers for patch structs: structs whose fields
+are nup.Update or nup.SliceUpdate values.
+
+On the server side, DecodePatch decodes the body of a PATCH request into a patch
+struct, accepting JSON, JSON Merge Patch (RFC 7396), and JSON Patch (RFC 6902)
+bodies. Its errors are Problems, which describe the failure in the RFC 9457
+"problem details" format, including the JSON pointers of any offending fields,
+and can be written to the response with WriteProblem:
+
+	func (s *Server) patchUser(w http.ResponseWriter, r *http.Request) {
+		var patch UserPatch
+		if err := nuphttp.DecodePatch(r, &patch); err != nil {
+			nuphttp.WriteProblem(w, err)
+			return
+		}
+		...
+	}
+
+PatchHandler goes further, implementing a complete PATCH endpoint: it loads the
+target model, checks the request's If-Match header against the model's ETag,
+decodes the patch, and applies and saves it, skipping the save if the patch
+wouldn't change the model.
+
+On the client side, NewPatchRequest builds the corresponding PATCH request from
+two snapshots of a model, sending only the fields that differ, and making the
+request conditional on the model being unchanged on the server.
+*/
+package nuphttp
@@ -0,0 +1,165 @@
+package nuphttp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/nicheinc/nullable/v2/nup"
+)
+
+// ETag returns a strong entity tag for model, including the surrounding
+// quotes, e.g. `"dTPrQarDwB6JQwVzwBrzKXuoPun4QiMs4GyhEA-ZT_s"`. The tag is
+// derived from nup.Hash, a digest of the canonical JSON encoding of model, so
+// models with equivalent JSON encodings have equal tags, regardless of key
+// order.
+func ETag(model interface{}) (string, error) {
+	sum, err := nup.Hash(model)
+	if err != nil {
+		return "", fmt.Errorf("nuphttp: computing ETag: %w", err)
+	}
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:]) + `"`, nil
+}
+
+// matchesIfMatch reports whether the value of an If-Match header matches the
+// entity tag etag, using the strong comparison required by RFC 9110: "*"
+// matches any tag, and weak tags never match.
+func matchesIfMatch(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// PatchHandler is an http.Handler for PATCH requests that applies a patch
+// struct of type P to a model of type M, a struct or a pointer to one, with
+// optimistic concurrency control based on the model's ETag. The patch is
+// applied to a copy of the loaded model made with nup.CopyOnWrite, so the
+// loaded model, which may be shared, e.g. by a cache, is left unchanged,
+// including any structs it points to. It handles a request as follows:
+//  1. It loads the current model with Load.
+//  2. If the request has an If-Match header, it checks it against the ETag of
+//     the current model, responding 412 (Precondition Failed) if it doesn't
+//     match. If the request has no If-Match header and RequireIfMatch is set,
+//     it responds 428 (Precondition Required).
+//  3. It decodes the patch from the request body with Decoder.DecodePatch.
+//  4. It drops the changes that the patch wouldn't make, using nup.DiffPatch.
+//     If none remain, it responds 204 (No Content) without calling Save.
+//  5. Otherwise, it applies the patch to the model with nup.ApplyPatch, saves
+//     it with Save, and responds 200 (OK) with the JSON encoding of the saved
+//     model.
+//
+// Successful responses carry the ETag of the resulting model. Errors are
+// written with WriteProblem, so Load and Save may return a *Problem, e.g. one
+// with status 404 (Not Found), to control the response.
+//
+// Since the model may change between Load and Save, Save should itself check
+// that the stored model is unchanged, e.g. using a version column, and return
+// a *Problem with status 412 if it isn't.
+type PatchHandler[M, P any] struct {
+	// Load loads the model that the request targets.
+	Load func(r *http.Request) (M, error)
+	// Save saves the patched model.
+	Save func(r *http.Request, model M) error
+	// Decoder decodes the patch from the request body.
+	Decoder Decoder
+	// RequireIfMatch causes requests without an If-Match header to be
+	// rejected, so that clients can't overwrite changes they haven't seen.
+	RequireIfMatch bool
+}
+
+// ServeHTTP implements http.Handler.
+func (h PatchHandler[M, P]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Accept-Patch", AcceptPatch)
+	if r.Method != http.MethodPatch {
+		w.Header().Set("Allow", http.MethodPatch)
+		WriteProblem(w, newProblem(http.StatusMethodNotAllowed, ""))
+		return
+	}
+	model, err := h.Load(r)
+	if err != nil {
+		WriteProblem(w, err)
+		return
+	}
+	etag, err := ETag(model)
+	if err != nil {
+		WriteProblem(w, err)
+		return
+	}
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if !matchesIfMatch(ifMatch, etag) {
+			w.Header().Set("ETag", etag)
+			WriteProblem(w, newProblem(http.StatusPreconditionFailed, "The resource has been modified since it was retrieved."))
+			return
+		}
+	} else if h.RequireIfMatch {
+		WriteProblem(w, newProblem(http.StatusPreconditionRequired, "The request requires an If-Match header."))
+		return
+	}
+
+	var patch P
+	if err := h.Decoder.DecodePatch(r, &patch); err != nil {
+		WriteProblem(w, err)
+		return
+	}
+	changed, err := nup.DiffPatch(&patch, model)
+	if err != nil {
+		WriteProblem(w, err)
+		return
+	}
+	if !changed {
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if model, err = applyPatch(model, patch); err != nil {
+		WriteProblem(w, err)
+		return
+	}
+	if err := h.Save(r, model); err != nil {
+		WriteProblem(w, err)
+		return
+	}
+	if etag, err = ETag(model); err != nil {
+		WriteProblem(w, err)
+		return
+	}
+	data, err := json.Marshal(model)
+	if err != nil {
+		WriteProblem(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", JSONContentType)
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// applyPatch applies patch to a copy of model, a struct or a pointer to one,
+// made with nup.CopyOnWrite so that model is left unchanged, and returns the
+// copy.
+func applyPatch[M, P any](model M, patch P) (M, error) {
+	var (
+		patched = nup.CopyOnWrite(model, patch)
+		target  interface{}
+	)
+	switch v := reflect.ValueOf(&patched).Elem(); {
+	case v.Kind() != reflect.Pointer:
+		target = &patched
+	case v.IsNil():
+		return model, errors.New("nuphttp: cannot patch a nil model")
+	default:
+		target = patched
+	}
+	if err := nup.ApplyPatch(target, patch); err != nil {
+		return model, err
+	}
+	return patched, nil
+}
@@ -0,0 +1,348 @@
+package nuphttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nicheinc/expect"
+
+	"github.com/nicheinc/nullable/v2/nup"
+)
+
+type testAddress struct {
+	City string `json:"city"`
+	Zip  string `json:"zip"`
+}
+
+type testModel struct {
+	Name    string      `json:"name"`
+	Age     *int        `json:"age"`
+	Tags    []string    `json:"tags"`
+	Address testAddress `json:"address"`
+}
+
+func TestETag(t *testing.T) {
+	etag, err := ETag(testModel{Name: "Alice"})
+	expect.ErrorNil(t, err)
+	expect.Equal(t, etag, `"dTPrQarDwB6JQwVzwBrzKXuoPun4QiMs4GyhEA-ZT_s"`)
+
+	same, err := ETag(&testModel{Name: "Alice"})
+	expect.ErrorNil(t, err)
+	expect.Equal(t, same, etag)
+
+	different, err := ETag(testModel{Name: "Bob"})
+	expect.ErrorNil(t, err)
+	expect.Equal(t, different == etag, false)
+
+	_, err = ETag(func() {})
+	expect.ErrorNonNil(t, err)
+}
+
+func TestMatchesIfMatch(t *testing.T) {
+	const etag = `"abc"`
+	testCases := []struct {
+		name     string
+		header   string
+		expected bool
+	}{
+		{
+			name:     "Match",
+			header:   `"abc"`,
+			expected: true,
+		},
+		{
+			name:     "Mismatch",
+			header:   `"xyz"`,
+			expected: false,
+		},
+		{
+			name:     "List",
+			header:   `"xyz", "abc"`,
+			expected: true,
+		},
+		{
+			name:     "Wildcard",
+			header:   `*`,
+			expected: true,
+		},
+		{
+			name:     "Weak",
+			header:   `W/"abc"`,
+			expected: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			expect.Equal(t, matchesIfMatch(testCase.header, etag), testCase.expected)
+		})
+	}
+}
+
+// testStore is a PatchHandler backend holding a single model.
+type testStore struct {
+	model   testModel
+	saves   int
+	loadErr error
+	saveErr error
+}
+
+func (s *testStore) handler(requireIfMatch bool) PatchHandler[testModel, testPatch] {
+	return PatchHandler[testModel, testPatch]{
+		Load: func(*http.Request) (testModel, error) {
+			return s.model, s.loadErr
+		},
+		Save: func(_ *http.Request, model testModel) error {
+			if s.saveErr != nil {
+				return s.saveErr
+			}
+			s.model = model
+			s.saves++
+			return nil
+		},
+		RequireIfMatch: requireIfMatch,
+	}
+}
+
+func TestPatchHandler(t *testing.T) {
+	var (
+		age      = 30
+		original = testModel{Name: "Alice", Age: &age, Address: testAddress{City: "Paris"}}
+		patched  = testModel{Name: "Bob", Address: testAddress{City: "Paris", Zip: "75001"}}
+	)
+	originalETag, err := ETag(original)
+	expect.ErrorNil(t, err)
+	patchedETag, err := ETag(patched)
+	expect.ErrorNil(t, err)
+
+	testCases := []struct {
+		name           string
+		method         string
+		requireIfMatch bool
+		ifMatch        string
+		body           string
+		loadErr        error
+		saveErr        error
+		expectedStatus int
+		expectedETag   string
+		expectedModel  testModel
+		expectedSaves  int
+	}{
+		{
+			name:           "Applied",
+			ifMatch:        originalETag,
+			body:           `{"name":"Bob","age":null,"address":{"zip":"75001"}}`,
+			expectedStatus: http.StatusOK,
+			expectedETag:   patchedETag,
+			expectedModel:  patched,
+			expectedSaves:  1,
+		},
+		{
+			name:           "AppliedWithoutIfMatch",
+			body:           `{"name":"Bob","age":null,"address":{"zip":"75001"}}`,
+			expectedStatus: http.StatusOK,
+			expectedETag:   patchedETag,
+			expectedModel:  patched,
+			expectedSaves:  1,
+		},
+		{
+			name:           "AppliedWithWildcard",
+			requireIfMatch: true,
+			ifMatch:        "*",
+			body:           `{"name":"Bob","age":null,"address":{"zip":"75001"}}`,
+			expectedStatus: http.StatusOK,
+			expectedETag:   patchedETag,
+			expectedModel:  patched,
+			expectedSaves:  1,
+		},
+		{
+			name:           "NoEffectiveChanges",
+			ifMatch:        originalETag,
+			body:           `{"name":"Alice","age":30,"tags":null}`,
+			expectedStatus: http.StatusNoContent,
+			expectedETag:   originalETag,
+			expectedModel:  original,
+		},
+		{
+			name:           "PreconditionFailed",
+			ifMatch:        `"stale"`,
+			body:           `{"name":"Bob"}`,
+			expectedStatus: http.StatusPreconditionFailed,
+			expectedETag:   originalETag,
+			expectedModel:  original,
+		},
+		{
+			name:           "PreconditionRequired",
+			requireIfMatch: true,
+			body:           `{"name":"Bob"}`,
+			expectedStatus: http.StatusPreconditionRequired,
+			expectedModel:  original,
+		},
+		{
+			name:           "InvalidPatch",
+			ifMatch:        originalETag,
+			body:           `{"name":null}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedModel:  original,
+		},
+		{
+			name:           "MethodNotAllowed",
+			method:         http.MethodPut,
+			body:           `{"name":"Bob"}`,
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedModel:  original,
+		},
+		{
+			name:           "LoadProblem",
+			body:           `{"name":"Bob"}`,
+			loadErr:        newProblem(http.StatusNotFound, ""),
+			expectedStatus: http.StatusNotFound,
+			expectedModel:  original,
+		},
+		{
+			name:           "SaveError",
+			body:           `{"name":"Bob"}`,
+			saveErr:        errors.New("database is down"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedModel:  original,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			store := &testStore{
+				model:   original,
+				loadErr: testCase.loadErr,
+				saveErr: testCase.saveErr,
+			}
+			method := testCase.method
+			if method == "" {
+				method = http.MethodPatch
+			}
+			r := httptest.NewRequest(method, "/users/1", strings.NewReader(testCase.body))
+			r.Header.Set("Content-Type", MergePatchContentType)
+			if testCase.ifMatch != "" {
+				r.Header.Set("If-Match", testCase.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			store.handler(testCase.requireIfMatch).ServeHTTP(w, r)
+
+			expect.Equal(t, w.Code, testCase.expectedStatus)
+			expect.Equal(t, w.Header().Get("ETag"), testCase.expectedETag)
+			expect.Equal(t, w.Header().Get("Accept-Patch"), AcceptPatch)
+			expect.Equal(t, store.model, testCase.expectedModel)
+			expect.Equal(t, store.saves, testCase.expectedSaves)
+			if testCase.expectedStatus == http.StatusOK {
+				expect.Equal(t, w.Body.String(), `{"name":"Bob","age":null,"tags":null,"address":{"city":"Paris","zip":"75001"}}`)
+			}
+		})
+	}
+}
+
+func TestPatchHandler_PointerModel(t *testing.T) {
+	var (
+		loaded = &testModel{Name: "Alice", Address: testAddress{City: "Paris"}}
+		saved  *testModel
+	)
+	handler := PatchHandler[*testModel, testPatch]{
+		Load: func(*http.Request) (*testModel, error) {
+			return loaded, nil
+		},
+		Save: func(_ *http.Request, model *testModel) error {
+			saved = model
+			return nil
+		},
+	}
+	r := httptest.NewRequest(http.MethodPatch, "/users/1", strings.NewReader(`{"name":"Bob"}`))
+	r.Header.Set("Content-Type", MergePatchContentType)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	expect.Equal(t, w.Code, http.StatusOK)
+	expect.Equal(t, saved, &testModel{Name: "Bob", Address: testAddress{City: "Paris"}})
+	// The loaded model is left unchanged.
+	expect.Equal(t, loaded.Name, "Alice")
+}
+
+// TestPatchHandler_NestedPointer ensures that patching a field within a struct
+// that the loaded model points to leaves the loaded model unchanged.
+func TestPatchHandler_NestedPointer(t *testing.T) {
+	type dbConfig struct {
+		Host string `json:"host"`
+	}
+	type config struct {
+		Name string    `json:"name"`
+		DB   *dbConfig `json:"db"`
+	}
+	type configPatch struct {
+		DB struct {
+			Host nup.Update[string] `json:"host,omitzero"`
+		} `json:"db,omitzero"`
+	}
+	var (
+		loadedDB = &dbConfig{Host: "old"}
+		loaded   = config{Name: "app", DB: loadedDB}
+	)
+	testCases := []struct {
+		name    string
+		handler http.Handler
+	}{
+		{
+			name: "Struct",
+			handler: PatchHandler[config, configPatch]{
+				Load: func(*http.Request) (config, error) {
+					return loaded, nil
+				},
+				Save: func(*http.Request, config) error {
+					return nil
+				},
+			},
+		},
+		{
+			name: "Pointer",
+			handler: PatchHandler[*config, configPatch]{
+				Load: func(*http.Request) (*config, error) {
+					return &loaded, nil
+				},
+				Save: func(*http.Request, *config) error {
+					return nil
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPatch, "/config", strings.NewReader(`{"db":{"host":"new"}}`))
+			r.Header.Set("Content-Type", MergePatchContentType)
+			w := httptest.NewRecorder()
+			testCase.handler.ServeHTTP(w, r)
+
+			expect.Equal(t, w.Code, http.StatusOK)
+			expect.Equal(t, w.Body.String(), `{"name":"app","db":{"host":"new"}}`)
+			expect.Equal(t, loaded.DB, loadedDB)
+			expect.Equal(t, loadedDB.Host, "old")
+		})
+	}
+}
+
+func TestPatchHandler_NilPointerModel(t *testing.T) {
+	handler := PatchHandler[*testModel, testPatch]{
+		Load: func(*http.Request) (*testModel, error) {
+			return nil, nil
+		},
+		Save: func(*http.Request, *testModel) error {
+			t.Error("Save called")
+			return nil
+		},
+	}
+	r := httptest.NewRequest(http.MethodPatch, "/users/1", strings.NewReader(`{"name":"Bob"}`))
+	r.Header.Set("Content-Type", MergePatchContentType)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	expect.Equal(t, w.Code, http.StatusInternalServerError)
+}
@@ -0,0 +1,188 @@
+package nuphttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nicheinc/nullable/v2/nup"
+)
+
+// jsonPatchOperation is an operation of a JSON Patch (RFC 6902).
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// pointerUnescaper unescapes the reference tokens of a JSON pointer.
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// mergePatcher converts a JSON Patch document to a JSON Merge Patch document
+// for a particular patch struct.
+type mergePatcher struct {
+	// updates holds the JSON pointers of the patch struct's Update and
+	// SliceUpdate fields, whose values can only be replaced as a whole.
+	updates map[string]bool
+	// opts are the options the merge patch will be decoded with.
+	opts nup.DecodeOptions
+	// paths holds the paths of the operations converted so far.
+	paths map[string]bool
+}
+
+// mergePatch converts a JSON Patch document, consisting only of "add",
+// "replace", and "remove" operations on the fields of the patch struct pointed
+// to by patch, to the equivalent JSON Merge Patch document, which can be
+// decoded with nup.Decode using opts. The operations are applied in order, so
+// that later operations on the same path take precedence, unless
+// opts.DisallowDuplicateKeys is set, in which case they're rejected, as are
+// duplicate keys in objects that later operations modify.
+//
+// Operations on elements of arrays, e.g. "/tags/0" or "/tags/-", or on members
+// of objects held by Update fields are rejected, since a patch struct can't
+// represent them.
+func mergePatch(data []byte, patch interface{}, opts nup.DecodeOptions) ([]byte, error) {
+	var operations []jsonPatchOperation
+	if err := json.Unmarshal(data, &operations); err != nil {
+		return nil, err
+	}
+	if operations == nil {
+		return nil, errors.New("document must be an array, not null")
+	}
+	m := mergePatcher{
+		updates: map[string]bool{},
+		opts:    opts,
+		paths:   map[string]bool{},
+	}
+	for _, field := range nup.Fields(patch) {
+		m.updates[field.Path] = true
+	}
+	merge := map[string]interface{}{}
+	for i, operation := range operations {
+		if err := m.addOperation(merge, operation); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return json.Marshal(merge)
+}
+
+// addOperation adds a JSON Patch operation to a JSON Merge Patch object.
+func (m mergePatcher) addOperation(merge map[string]interface{}, operation jsonPatchOperation) error {
+	var value json.RawMessage
+	switch operation.Op {
+	case "add", "replace":
+		if operation.Value == nil {
+			return fmt.Errorf(`%q operation is missing "value"`, operation.Op)
+		}
+		value = operation.Value
+	case "remove":
+		value = json.RawMessage("null")
+	case "":
+		return errors.New(`operation is missing "op"`)
+	default:
+		return fmt.Errorf("%q operations are not supported", operation.Op)
+	}
+	if operation.Path == nil {
+		return errors.New(`operation is missing "path"`)
+	}
+	path := *operation.Path
+	if path == "" {
+		return errors.New("operations on the whole document are not supported")
+	}
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("path %q is not a JSON pointer", path)
+	}
+	if err := m.checkPath(path); err != nil {
+		return err
+	}
+	tokens := strings.Split(path[1:], "/")
+	object := merge
+	for i, token := range tokens[:len(tokens)-1] {
+		var err error
+		if object, err = m.childObject(object, pointerUnescaper.Replace(token)); err != nil {
+			return fmt.Errorf("value at %q: %w", "/"+strings.Join(tokens[:i+1], "/"), err)
+		}
+	}
+	object[pointerUnescaper.Replace(tokens[len(tokens)-1])] = value
+	return nil
+}
+
+// checkPath returns an error if the operation path addresses something other
+// than a field of the patch struct, or a field addressed by an earlier
+// operation when duplicates are disallowed.
+func (m mergePatcher) checkPath(path string) error {
+	for i := 1; i < len(path); i++ {
+		if path[i] == '/' && m.updates[path[:i]] {
+			return fmt.Errorf("path %q addresses an element or member of %s, which is not supported", path, path[:i])
+		}
+	}
+	for _, token := range strings.Split(path[1:], "/") {
+		if token == "-" {
+			return fmt.Errorf("path %q addresses an array element, which is not supported", path)
+		}
+	}
+	if m.opts.DisallowDuplicateKeys {
+		if m.paths[path] {
+			return fmt.Errorf("path %q is changed by more than one operation", path)
+		}
+		m.paths[path] = true
+	}
+	return nil
+}
+
+// childObject returns the member of object with the given key as an object,
+// replacing it with one if it isn't already. A member holding a JSON object,
+// added by an earlier operation, is converted so that later operations can
+// modify it.
+func (m mergePatcher) childObject(object map[string]interface{}, key string) (map[string]interface{}, error) {
+	switch member := object[key].(type) {
+	case map[string]interface{}:
+		return member, nil
+	case json.RawMessage:
+		var members map[string]json.RawMessage
+		if json.Unmarshal(member, &members) == nil && members != nil {
+			if m.opts.DisallowDuplicateKeys {
+				if duplicate, ok := duplicateKey(member); ok {
+					return nil, fmt.Errorf("duplicate key %q", duplicate)
+				}
+			}
+			child := make(map[string]interface{}, len(members))
+			for key, value := range members {
+				child[key] = value
+			}
+			object[key] = child
+			return child, nil
+		}
+	}
+	child := map[string]interface{}{}
+	object[key] = child
+	return child, nil
+}
+
+// duplicateKey returns the first key that occurs more than once in the JSON
+// object data, if any.
+func duplicateKey(data []byte) (string, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return "", false
+	}
+	keys := map[string]bool{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", false
+		}
+		key, _ := token.(string)
+		if keys[key] {
+			return key, true
+		}
+		keys[key] = true
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return "", false
+		}
+	}
+	return "", false
+}
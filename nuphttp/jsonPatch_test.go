@@ -0,0 +1,56 @@
+package nuphttp
+
+import (
+	"testing"
+
+	"github.com/nicheinc/expect"
+
+	"github.com/nicheinc/nullable/v2/nup"
+)
+
+func TestMergePatch(t *testing.T) {
+	testCases := []struct {
+		name     string
+		patch    string
+		expected string
+	}{
+		{
+			name:     "Empty",
+			patch:    `[]`,
+			expected: `{}`,
+		},
+		{
+			name:     "Operations",
+			patch:    `[{"op":"add","path":"/a","value":1},{"op":"remove","path":"/b"},{"op":"replace","path":"/c/d","value":"x"}]`,
+			expected: `{"a":1,"b":null,"c":{"d":"x"}}`,
+		},
+		{
+			name:     "EscapedPath",
+			patch:    `[{"op":"add","path":"/a~1b/c~0d","value":true}]`,
+			expected: `{"a/b":{"c~d":true}}`,
+		},
+		{
+			name:     "NestedAfterObject",
+			patch:    `[{"op":"add","path":"/a","value":{"b":1,"c":2}},{"op":"remove","path":"/a/c"}]`,
+			expected: `{"a":{"b":1,"c":null}}`,
+		},
+		{
+			name:     "NestedAfterScalar",
+			patch:    `[{"op":"remove","path":"/a"},{"op":"add","path":"/a/b","value":1}]`,
+			expected: `{"a":{"b":1}}`,
+		},
+		{
+			name:     "NullValue",
+			patch:    `[{"op":"replace","path":"/a","value":null}]`,
+			expected: `{"a":null}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, err := mergePatch([]byte(testCase.patch), nil, nup.DecodeOptions{})
+			expect.ErrorNil(t, err)
+			expect.Equal(t, string(actual), testCase.expected)
+		})
+	}
+}
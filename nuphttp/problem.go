@@ -0,0 +1,105 @@
+package nuphttp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/nicheinc/nullable/v2/nup"
+)
+
+// ProblemContentType is the media type of an RFC 9457 problem details object.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an error that describes the failure of an HTTP request as an RFC
+// 9457 problem details object.
+type Problem struct {
+	// Type is a URI reference identifying the problem type. If it's empty,
+	// the type is "about:blank", meaning the problem is described by Status
+	// alone.
+	Type string `json:"type,omitempty"`
+	// Title is a short summary of the problem type. For the "about:blank"
+	// type, it's the text of the status code, e.g. "Unsupported Media Type".
+	Title string `json:"title"`
+	// Status is the HTTP status code.
+	Status int `json:"status"`
+	// Detail explains this occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+	// Instance is a URI reference identifying this occurrence of the problem.
+	Instance string `json:"instance,omitempty"`
+	// Errors, an extension member, describes the problems with individual
+	// fields of the request body.
+	Errors []FieldProblem `json:"errors,omitempty"`
+}
+
+// FieldProblem describes a problem with a single field of a request body.
+type FieldProblem struct {
+	// Pointer is the JSON pointer to the field, e.g. "/address/city".
+	Pointer string `json:"pointer"`
+	// Detail explains the problem.
+	Detail string `json:"detail"`
+}
+
+// newProblem returns a Problem of the "about:blank" type with the given status
+// and detail.
+func newProblem(status int, detail string) *Problem {
+	return &Problem{
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// fieldProblem returns a Problem with status 422 (Unprocessable Content)
+// describing errs.
+func fieldProblem(errs nup.FieldErrors) *Problem {
+	problem := newProblem(http.StatusUnprocessableEntity, "The request body has invalid fields.")
+	problem.Errors = make([]FieldProblem, len(errs))
+	for i, err := range errs {
+		detail := err.Err.Error()
+		if err.Op != nup.OpNoop {
+			detail = "cannot " + err.Op.String() + ": " + detail
+		}
+		problem.Errors[i] = FieldProblem{
+			Pointer: err.Path,
+			Detail:  detail,
+		}
+	}
+	return problem
+}
+
+// Error implements error, returning the title and detail and the details of
+// any field problems.
+func (p *Problem) Error() string {
+	var builder strings.Builder
+	builder.WriteString("nuphttp: ")
+	builder.WriteString(p.Title)
+	if p.Detail != "" {
+		builder.WriteString(": ")
+		builder.WriteString(p.Detail)
+	}
+	for _, field := range p.Errors {
+		builder.WriteString("; ")
+		builder.WriteString(field.Pointer)
+		builder.WriteString(": ")
+		builder.WriteString(field.Detail)
+	}
+	return builder.String()
+}
+
+// WriteProblem writes err to w as a problem details object. If err isn't, and
+// doesn't wrap, a *Problem, a generic problem with status 500 (Internal Server
+// Error) is written instead, so that the details of unexpected errors aren't
+// disclosed.
+func WriteProblem(w http.ResponseWriter, err error) {
+	var problem *Problem
+	if !errors.As(err, &problem) {
+		problem = newProblem(http.StatusInternalServerError, "")
+	}
+	// A Problem holds only strings and ints, so it always marshals.
+	data, _ := json.Marshal(problem)
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	w.Write(data)
+}
@@ -0,0 +1,94 @@
+package nuphttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nicheinc/expect"
+)
+
+func TestProblem_Error(t *testing.T) {
+	testCases := []struct {
+		name     string
+		problem  *Problem
+		expected string
+	}{
+		{
+			name:     "TitleOnly",
+			problem:  newProblem(http.StatusInternalServerError, ""),
+			expected: "nuphttp: Internal Server Error",
+		},
+		{
+			name:     "Detail",
+			problem:  newProblem(http.StatusBadRequest, "Invalid request body."),
+			expected: "nuphttp: Bad Request: Invalid request body.",
+		},
+		{
+			name: "Fields",
+			problem: &Problem{
+				Title:  "Unprocessable Entity",
+				Status: http.StatusUnprocessableEntity,
+				Detail: "The request body has invalid fields.",
+				Errors: []FieldProblem{
+					{Pointer: "/name", Detail: "cannot remove: null not allowed"},
+					{Pointer: "/age", Detail: "cannot set: unknown key"},
+				},
+			},
+			expected: "nuphttp: Unprocessable Entity: The request body has invalid fields.; /name: cannot remove: null not allowed; /age: cannot set: unknown key",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			expect.Equal(t, testCase.problem.Error(), testCase.expected)
+		})
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	testCases := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Problem",
+			err:            newProblem(http.StatusUnsupportedMediaType, "The Content-Type header is missing."),
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedBody:   `{"title":"Unsupported Media Type","status":415,"detail":"The Content-Type header is missing."}`,
+		},
+		{
+			name: "WrappedProblem",
+			err: fmt.Errorf("patching user: %w", &Problem{
+				Type:   "https://example.com/problems/invalid-fields",
+				Title:  "Invalid fields",
+				Status: http.StatusUnprocessableEntity,
+				Errors: []FieldProblem{
+					{Pointer: "/name", Detail: "cannot remove: null not allowed"},
+				},
+			}),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"type":"https://example.com/problems/invalid-fields","title":"Invalid fields","status":422,"errors":[{"pointer":"/name","detail":"cannot remove: null not allowed"}]}`,
+		},
+		{
+			name:           "OtherError",
+			err:            errors.New("database is down"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"title":"Internal Server Error","status":500}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			WriteProblem(w, testCase.err)
+			expect.Equal(t, w.Code, testCase.expectedStatus)
+			expect.Equal(t, w.Header().Get("Content-Type"), ProblemContentType)
+			expect.Equal(t, w.Body.String(), testCase.expectedBody)
+		})
+	}
+}
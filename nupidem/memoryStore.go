@@ -0,0 +1,118 @@
+package nupidem
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store that keeps records in memory, for a limited time. It's
+// safe for concurrent use.
+type MemoryStore[R any] struct {
+	ttl time.Duration
+	// now returns the current time; it's replaced in tests.
+	now func() time.Time
+
+	mu        sync.Mutex
+	records   map[string]memoryRecord[R]
+	lastSweep time.Time
+	// tokens counts the reservations made, to generate their tokens.
+	tokens uint64
+}
+
+type memoryRecord[R any] struct {
+	Record[R]
+	expires time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore that keeps each record for ttl
+// after it's reserved, and again for ttl after it's completed. Expired records
+// are ignored, and are evicted periodically. It returns an error if ttl isn't
+// positive, since records would then expire immediately, and duplicate
+// requests would never be detected.
+func NewMemoryStore[R any](ttl time.Duration) (*MemoryStore[R], error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("nupidem: MemoryStore requires a positive TTL, not %v", ttl)
+	}
+	return &MemoryStore[R]{
+		ttl:     ttl,
+		now:     time.Now,
+		records: map[string]memoryRecord[R]{},
+	}, nil
+}
+
+// Reserve implements Store.
+func (s *MemoryStore[R]) Reserve(_ context.Context, key string, hash Hash) (Record[R], bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+	if record, ok := s.records[key]; ok && now.Before(record.expires) {
+		found := record.Record
+		found.Token = ""
+		return found, true, nil
+	}
+	s.tokens++
+	record := Record[R]{
+		Hash:  hash,
+		Token: strconv.FormatUint(s.tokens, 10),
+	}
+	s.records[key] = memoryRecord[R]{
+		Record:  record,
+		expires: now.Add(s.ttl),
+	}
+	return record, false, nil
+}
+
+// Complete implements Store. It records the result even if the reservation
+// has expired, provided the key hasn't been reserved again, or its record
+// evicted, in the meantime. Otherwise, it returns an error.
+func (s *MemoryStore[R]) Complete(_ context.Context, key, token string, result R) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	record, ok := s.records[key]
+	if !ok || record.Done || record.Token != token {
+		return fmt.Errorf("nupidem: idempotency key %q is not reserved", key)
+	}
+	record.Done = true
+	record.Result = result
+	record.expires = now.Add(s.ttl)
+	s.records[key] = record
+	return nil
+}
+
+// Release implements Store. Releasing a key that isn't reserved with token
+// does nothing.
+func (s *MemoryStore[R]) Release(_ context.Context, key, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if record, ok := s.records[key]; ok && !record.Done && record.Token == token {
+		delete(s.records, key)
+	}
+	return nil
+}
+
+// Len returns the number of records in the store, including any expired
+// records that haven't yet been evicted.
+func (s *MemoryStore[R]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.records)
+}
+
+// sweep evicts expired records, at most once per TTL, so that the cost of
+// eviction is amortized over many calls.
+func (s *MemoryStore[R]) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.ttl {
+		return
+	}
+	for key, record := range s.records {
+		if !now.Before(record.expires) {
+			delete(s.records, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,241 @@
+package nupidem
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nicheinc/expect"
+)
+
+// testClock is a manually advanced clock for MemoryStore.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+// newMemoryStore returns a new MemoryStore, failing the test if it can't.
+func newMemoryStore[R any](t *testing.T, ttl time.Duration) *MemoryStore[R] {
+	t.Helper()
+	store, err := NewMemoryStore[R](ttl)
+	expect.ErrorNil(t, err)
+	return store
+}
+
+func newTestStore(t *testing.T, ttl time.Duration) (*MemoryStore[string], *testClock) {
+	var (
+		store = newMemoryStore[string](t, ttl)
+		clock = &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	)
+	store.now = clock.Now
+	return store, clock
+}
+
+func TestNewMemoryStore_InvalidTTL(t *testing.T) {
+	testCases := []struct {
+		name string
+		ttl  time.Duration
+	}{
+		{
+			name: "Zero",
+			ttl:  0,
+		},
+		{
+			name: "Negative",
+			ttl:  -time.Minute,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			store, err := NewMemoryStore[string](testCase.ttl)
+			expect.ErrorNonNil(t, err)
+			expect.Equal(t, store, nil)
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	var (
+		ctx          = context.Background()
+		store, clock = newTestStore(t, time.Minute)
+		hash         = Hash{1}
+	)
+
+	record, found, err := store.Reserve(ctx, "key", hash)
+	expect.ErrorNil(t, err)
+	expect.Equal(t, found, false)
+	expect.Equal(t, record, Record[string]{Hash: hash, Token: "1"})
+
+	record, found, err = store.Reserve(ctx, "key", Hash{2})
+	expect.ErrorNil(t, err)
+	expect.Equal(t, found, true)
+	expect.Equal(t, record, Record[string]{Hash: hash})
+
+	clock.now = clock.now.Add(30 * time.Second)
+	err = store.Complete(ctx, "key", "1", "result")
+	expect.ErrorNil(t, err)
+
+	// Completion extends the record's lifetime.
+	clock.now = clock.now.Add(59 * time.Second)
+	record, found, err = store.Reserve(ctx, "key", hash)
+	expect.ErrorNil(t, err)
+	expect.Equal(t, found, true)
+	expect.Equal(t, record, Record[string]{Hash: hash, Done: true, Result: "result"})
+
+	// Release doesn't delete a completed record.
+	err = store.Release(ctx, "key", "1")
+	expect.ErrorNil(t, err)
+	expect.Equal(t, store.Len(), 1)
+
+	// Once it expires, the key can be reserved again.
+	clock.now = clock.now.Add(time.Second)
+	record, found, err = store.Reserve(ctx, "key", Hash{2})
+	expect.ErrorNil(t, err)
+	expect.Equal(t, found, false)
+	expect.Equal(t, record, Record[string]{Hash: Hash{2}, Token: "2"})
+}
+
+// TestMemoryStore_Reservation ensures that a reservation can only be completed
+// or released with its token, even once it has expired and the key has been
+// reserved again.
+func TestMemoryStore_Reservation(t *testing.T) {
+	var (
+		ctx          = context.Background()
+		store, clock = newTestStore(t, time.Minute)
+	)
+	old, _, err := store.Reserve(ctx, "key", Hash{1})
+	expect.ErrorNil(t, err)
+
+	clock.now = clock.now.Add(time.Minute)
+	current, found, err := store.Reserve(ctx, "key", Hash{2})
+	expect.ErrorNil(t, err)
+	expect.Equal(t, found, false)
+
+	err = store.Release(ctx, "key", old.Token)
+	expect.ErrorNil(t, err)
+	err = store.Complete(ctx, "key", old.Token, "old")
+	expect.ErrorNonNil(t, err)
+
+	err = store.Complete(ctx, "key", current.Token, "current")
+	expect.ErrorNil(t, err)
+	record, found, err := store.Reserve(ctx, "key", Hash{2})
+	expect.ErrorNil(t, err)
+	expect.Equal(t, found, true)
+	expect.Equal(t, record, Record[string]{Hash: Hash{2}, Done: true, Result: "current"})
+}
+
+func TestMemoryStore_Complete(t *testing.T) {
+	testCases := []struct {
+		name    string
+		prepare func(store *MemoryStore[string], clock *testClock)
+	}{
+		{
+			name:    "NotReserved",
+			prepare: func(*MemoryStore[string], *testClock) {},
+		},
+		{
+			name: "AlreadyCompleted",
+			prepare: func(store *MemoryStore[string], _ *testClock) {
+				store.Reserve(context.Background(), "key", Hash{})
+				store.Complete(context.Background(), "key", "1", "result")
+			},
+		},
+		{
+			name: "Released",
+			prepare: func(store *MemoryStore[string], _ *testClock) {
+				store.Reserve(context.Background(), "key", Hash{})
+				store.Release(context.Background(), "key", "1")
+			},
+		},
+		{
+			name: "Evicted",
+			prepare: func(store *MemoryStore[string], clock *testClock) {
+				store.Reserve(context.Background(), "key", Hash{})
+				clock.now = clock.now.Add(time.Minute)
+				store.Reserve(context.Background(), "other", Hash{})
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			store, clock := newTestStore(t, time.Minute)
+			testCase.prepare(store, clock)
+			err := store.Complete(context.Background(), "key", "1", "other")
+			expect.ErrorNonNil(t, err)
+		})
+	}
+}
+
+// TestMemoryStore_Complete_Expired ensures that an expired reservation can
+// still be completed if the key hasn't been reserved again.
+func TestMemoryStore_Complete_Expired(t *testing.T) {
+	var (
+		ctx          = context.Background()
+		store, clock = newTestStore(t, time.Minute)
+	)
+	reserved, _, err := store.Reserve(ctx, "key", Hash{1})
+	expect.ErrorNil(t, err)
+	clock.now = clock.now.Add(time.Minute)
+	err = store.Complete(ctx, "key", reserved.Token, "result")
+	expect.ErrorNil(t, err)
+	record, found, err := store.Reserve(ctx, "key", Hash{1})
+	expect.ErrorNil(t, err)
+	expect.Equal(t, found, true)
+	expect.Equal(t, record, Record[string]{Hash: Hash{1}, Done: true, Result: "result"})
+}
+
+func TestMemoryStore_Eviction(t *testing.T) {
+	var (
+		ctx          = context.Background()
+		store, clock = newTestStore(t, time.Minute)
+	)
+	for i := 0; i < 10; i++ {
+		store.Reserve(ctx, fmt.Sprint(i), Hash{})
+	}
+	expect.Equal(t, store.Len(), 10)
+
+	// Expired records are evicted once a TTL has passed since the last
+	// sweep.
+	clock.now = clock.now.Add(30 * time.Second)
+	store.Reserve(ctx, "new", Hash{})
+	clock.now = clock.now.Add(30 * time.Second)
+	store.Reserve(ctx, "newer", Hash{})
+	expect.Equal(t, store.Len(), 2)
+}
+
+// TestMemoryStore_Concurrent ensures that only one of many concurrent
+// reservations of a key succeeds. Run it with the race detector.
+func TestMemoryStore_Concurrent(t *testing.T) {
+	const goroutines = 32
+	var (
+		ctx      = context.Background()
+		store    = newMemoryStore[int](t, time.Hour)
+		mu       sync.Mutex
+		reserved int
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			record, found, err := store.Reserve(ctx, "key", Hash{})
+			expect.ErrorNil(t, err)
+			if !found {
+				mu.Lock()
+				reserved++
+				mu.Unlock()
+				expect.ErrorNil(t, store.Complete(ctx, "key", record.Token, 1))
+			}
+			store.Len()
+		}()
+	}
+	wg.Wait()
+	expect.Equal(t, reserved, 1)
+}
@@ -0,0 +1,133 @@
+/*
+Package nupidem makes the application of patches idempotent, so that a client
+can safely retry a request whose outcome it doesn't know, e.g. after a timeout.
+
+Each request carries an idempotency key, e.g. from an Idempotency-Key header,
+chosen by the client. Do records the key, along with the hash of the request's
+patch (see nup.Hash), in a Store, applies the patch, and records the result. A
+retry with the same key and the same patch replays the recorded result without
+applying the patch again, while a request that reuses the key for a different
+patch is rejected with ErrConflict. An empty key is rejected with ErrNoKey,
+rather than shared by every request without one; a server that doesn't require
+keys can apply such requests directly:
+
+	apply := func(ctx context.Context) (User, error) {
+		return users.Apply(ctx, id, patch)
+	}
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" {
+		// Without a key, the request can't be made idempotent.
+		return apply(ctx)
+	}
+	result, replayed, err := nupidem.Do(ctx, store, key, patch, apply)
+
+MemoryStore is an in-memory Store, suitable for a single process; other
+implementations can keep records in a shared database.
+*/
+package nupidem
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/nicheinc/nullable/v2/nup"
+)
+
+var (
+	// ErrConflict indicates an idempotency key that has already been used
+	// for a different patch.
+	ErrConflict = errors.New("nupidem: idempotency key was used for a different patch")
+	// ErrInProgress indicates an idempotency key whose patch is still being
+	// applied, by a concurrent request.
+	ErrInProgress = errors.New("nupidem: idempotency key is in use by a request in progress")
+	// ErrNoKey indicates an empty idempotency key, e.g. from a request without
+	// an Idempotency-Key header.
+	ErrNoKey = errors.New("nupidem: idempotency key is empty")
+)
+
+// Hash is a digest of a patch, as returned by nup.Hash.
+type Hash = [sha256.Size]byte
+
+// Record is the record of an idempotency key.
+type Record[R any] struct {
+	// Hash is the hash of the patch that the key was used for.
+	Hash Hash
+	// Token identifies a reservation of the key, so that only the request
+	// that made it can complete or release it, even after it has expired and
+	// the key has been reserved again. It's set only in the record that
+	// Reserve returns for a new reservation.
+	Token string
+	// Done indicates that the patch has been applied, with result Result.
+	// Until then, the key is reserved by the request applying it.
+	Done bool
+	// Result is the result of applying the patch.
+	Result R
+}
+
+// Store records the results of applying patches by idempotency key, for use by
+// Do. A Store must be safe for concurrent use, and its methods must be atomic
+// with respect to one another.
+type Store[R any] interface {
+	// Reserve returns the record of key, if it has one. Otherwise, it
+	// reserves key by recording the hash of the patch about to be applied,
+	// and returns a record holding the reservation's token, and false.
+	Reserve(ctx context.Context, key string, hash Hash) (Record[R], bool, error)
+	// Complete records the result of applying the patch for key, which must
+	// still be reserved with token.
+	Complete(ctx context.Context, key, token string, result R) error
+	// Release deletes the record of key, whose patch couldn't be applied, so
+	// that it can be retried, if it's still reserved with token.
+	Release(ctx context.Context, key, token string) error
+}
+
+// Do calls apply to apply patch, unless it has already been applied under the
+// same idempotency key. Specifically, it returns:
+//   - the recorded result, and true, if key was already used for an equivalent
+//     patch, i.e. one with the same nup.Hash, which has been applied;
+//   - ErrInProgress if key was already used for an equivalent patch that's
+//     still being applied;
+//   - ErrConflict if key was already used for a different patch;
+//   - ErrNoKey, without calling apply, if key is empty;
+//   - otherwise, the result of apply, and false, after recording it under key.
+//
+// If apply returns an error, no result is recorded, so that the request may be
+// retried, and Do returns the error. If apply succeeds but the result can't be
+// recorded, e.g. because the reservation was lost, Do returns the result along
+// with the error, since the patch has been applied; the caller should report
+// success rather than invite a retry, which may apply the patch again.
+func Do[R any](ctx context.Context, store Store[R], key string, patch interface{}, apply func(context.Context) (R, error)) (R, bool, error) {
+	var zero R
+	if key == "" {
+		return zero, false, ErrNoKey
+	}
+	hash, err := nup.Hash(patch)
+	if err != nil {
+		return zero, false, err
+	}
+	record, found, err := store.Reserve(ctx, key, hash)
+	if err != nil {
+		return zero, false, err
+	}
+	if found {
+		switch {
+		case record.Hash != hash:
+			return zero, false, ErrConflict
+		case !record.Done:
+			return zero, false, ErrInProgress
+		default:
+			return record.Result, true, nil
+		}
+	}
+	result, err := apply(ctx)
+	if err != nil {
+		if releaseErr := store.Release(ctx, key, record.Token); releaseErr != nil {
+			return zero, false, errors.Join(err, releaseErr)
+		}
+		return zero, false, err
+	}
+	if err := store.Complete(ctx, key, record.Token, result); err != nil {
+		return result, false, err
+	}
+	return result, false, nil
+}
@@ -0,0 +1,244 @@
+package nupidem
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nicheinc/expect"
+
+	"github.com/nicheinc/nullable/v2/nup"
+)
+
+type testPatch struct {
+	Name nup.Update[string] `json:"name,omitzero"`
+	Age  nup.Update[int]    `json:"age,omitzero"`
+}
+
+func TestDo(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		store = newMemoryStore[string](t, time.Hour)
+		calls int
+	)
+	apply := func(result string) func(context.Context) (string, error) {
+		return func(context.Context) (string, error) {
+			calls++
+			return result, nil
+		}
+	}
+
+	// The first request applies the patch.
+	result, replayed, err := Do(ctx, store, "key", testPatch{Name: nup.Set("Alice")}, apply("first"))
+	expect.ErrorNil(t, err)
+	expect.Equal(t, result, "first")
+	expect.Equal(t, replayed, false)
+	expect.Equal(t, calls, 1)
+
+	// A retry with an equivalent patch replays the result.
+	result, replayed, err = Do(ctx, store, "key", &testPatch{Name: nup.Set("Alice")}, apply("second"))
+	expect.ErrorNil(t, err)
+	expect.Equal(t, result, "first")
+	expect.Equal(t, replayed, true)
+	expect.Equal(t, calls, 1)
+
+	// Reusing the key for a different patch is a conflict.
+	result, replayed, err = Do(ctx, store, "key", testPatch{Name: nup.Set("Bob")}, apply("third"))
+	expect.ErrorIs(ErrConflict)(t, err)
+	expect.Equal(t, result, "")
+	expect.Equal(t, replayed, false)
+	expect.Equal(t, calls, 1)
+
+	// Another key applies the patch again.
+	result, replayed, err = Do(ctx, store, "other", testPatch{Name: nup.Set("Alice")}, apply("fourth"))
+	expect.ErrorNil(t, err)
+	expect.Equal(t, result, "fourth")
+	expect.Equal(t, replayed, false)
+	expect.Equal(t, calls, 2)
+}
+
+func TestDo_InProgress(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		store   = newMemoryStore[int](t, time.Hour)
+		patch   = testPatch{Age: nup.Set(30)}
+		started = make(chan struct{})
+		finish  = make(chan struct{})
+		done    = make(chan struct{})
+	)
+	go func() {
+		defer close(done)
+		_, _, err := Do(ctx, store, "key", patch, func(context.Context) (int, error) {
+			close(started)
+			<-finish
+			return 1, nil
+		})
+		expect.ErrorNil(t, err)
+	}()
+	<-started
+	_, _, err := Do(ctx, store, "key", patch, func(context.Context) (int, error) {
+		t.Error("patch applied twice")
+		return 2, nil
+	})
+	expect.ErrorIs(ErrInProgress)(t, err)
+	close(finish)
+	<-done
+
+	result, replayed, err := Do(ctx, store, "key", patch, func(context.Context) (int, error) {
+		t.Error("patch applied twice")
+		return 3, nil
+	})
+	expect.ErrorNil(t, err)
+	expect.Equal(t, result, 1)
+	expect.Equal(t, replayed, true)
+}
+
+func TestDo_ApplyError(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		store    = newMemoryStore[int](t, time.Hour)
+		patch    = testPatch{Age: nup.Set(30)}
+		errApply = errors.New("apply failed")
+	)
+	_, _, err := Do(ctx, store, "key", patch, func(context.Context) (int, error) {
+		return 0, errApply
+	})
+	expect.ErrorIs(errApply)(t, err)
+	expect.Equal(t, store.Len(), 0)
+
+	// The failed request can be retried.
+	result, replayed, err := Do(ctx, store, "key", patch, func(context.Context) (int, error) {
+		return 1, nil
+	})
+	expect.ErrorNil(t, err)
+	expect.Equal(t, result, 1)
+	expect.Equal(t, replayed, false)
+}
+
+// TestDo_EmptyKey ensures that requests without a key don't share one.
+func TestDo_EmptyKey(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		store = newMemoryStore[int](t, time.Hour)
+		calls int
+	)
+	for i := 0; i < 2; i++ {
+		result, replayed, err := Do(ctx, store, "", testPatch{Age: nup.Set(i)}, func(context.Context) (int, error) {
+			calls++
+			return i, nil
+		})
+		expect.ErrorIs(ErrNoKey)(t, err)
+		expect.Equal(t, result, 0)
+		expect.Equal(t, replayed, false)
+	}
+	expect.Equal(t, calls, 0)
+	expect.Equal(t, store.Len(), 0)
+}
+
+// errorStore is a Store whose methods fail.
+type errorStore struct {
+	reserveErr  error
+	completeErr error
+	releaseErr  error
+}
+
+func (s errorStore) Reserve(context.Context, string, Hash) (Record[int], bool, error) {
+	return Record[int]{}, false, s.reserveErr
+}
+
+func (s errorStore) Complete(context.Context, string, string, int) error {
+	return s.completeErr
+}
+
+func (s errorStore) Release(context.Context, string, string) error {
+	return s.releaseErr
+}
+
+func TestDo_Errors(t *testing.T) {
+	var (
+		errStore = errors.New("store failed")
+		errApply = errors.New("apply failed")
+	)
+	testCases := []struct {
+		name       string
+		store      Store[int]
+		patch      interface{}
+		applyErr   error
+		expected   int
+		errorCheck expect.ErrorCheck
+	}{
+		{
+			name:       "UnhashablePatch",
+			store:      errorStore{},
+			patch:      func() {},
+			errorCheck: expect.ErrorNonNil,
+		},
+		{
+			name:       "ReserveError",
+			store:      errorStore{reserveErr: errStore},
+			patch:      testPatch{},
+			errorCheck: expect.ErrorIs(errStore),
+		},
+		{
+			name:       "CompleteError",
+			store:      errorStore{completeErr: errStore},
+			patch:      testPatch{},
+			expected:   1,
+			errorCheck: expect.ErrorIs(errStore),
+		},
+		{
+			name:       "ReleaseError",
+			store:      errorStore{releaseErr: errStore},
+			patch:      testPatch{},
+			applyErr:   errApply,
+			errorCheck: expect.ErrorIsAll(errApply, errStore),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, _, err := Do(context.Background(), testCase.store, "key", testCase.patch, func(context.Context) (int, error) {
+				return 1, testCase.applyErr
+			})
+			testCase.errorCheck(t, err)
+			expect.Equal(t, result, testCase.expected)
+		})
+	}
+}
+
+// TestDo_Concurrent ensures that concurrent requests with the same key apply
+// the patch at most once. Run it with the race detector.
+func TestDo_Concurrent(t *testing.T) {
+	const (
+		keys       = 4
+		goroutines = 32
+	)
+	var (
+		ctx   = context.Background()
+		store = newMemoryStore[int64](t, time.Hour)
+		calls [keys]atomic.Int64
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := i % keys
+			result, _, err := Do(ctx, store, string(rune('a'+key)), testPatch{Age: nup.Set(key)}, func(context.Context) (int64, error) {
+				return calls[key].Add(1), nil
+			})
+			if err != nil {
+				expect.ErrorIs(ErrInProgress)(t, err)
+				return
+			}
+			expect.Equal(t, result, 1)
+		}(i)
+	}
+	wg.Wait()
+	for key := range calls {
+		expect.Equal(t, calls[key].Load(), 1)
+	}
+}
@@ -0,0 +1,273 @@
+/*
+Package nupstore provides Store, a versioned in-memory store of models that are
+modified by applying patch structs: structs whose fields are nup.Update or
+nup.SliceUpdate values corresponding to the fields of the model.
+
+Each model has a version, which starts at 1 and increases with each change.
+Patches are applied with optimistic concurrency control: Apply takes the version
+that the caller last saw and fails with ErrVersionMismatch if the model has
+changed since, in which case the caller can fetch the model again and retry:
+
+	for {
+		user, version, err := store.Get(id)
+		if err != nil {
+			return err
+		}
+		patch := computePatch(user)
+		_, _, err = store.Apply(id, version, patch)
+		if !errors.Is(err, nupstore.ErrVersionMismatch) {
+			return err
+		}
+	}
+
+Store is intended for tests and small services, and as a reference for applying
+patches correctly: it applies only the changes that a patch would actually make
+(see nup.DiffPatch), never modifies a model that it has returned, and records
+each change, along with the effective patch, in a bounded history.
+*/
+package nupstore
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/nicheinc/nullable/v2/nup"
+)
+
+var (
+	// ErrNotFound indicates a key that the store doesn't hold.
+	ErrNotFound = errors.New("nupstore: not found")
+	// ErrExists indicates a key that the store already holds, when creating
+	// a model.
+	ErrExists = errors.New("nupstore: already exists")
+	// ErrVersionMismatch indicates that a model's version isn't the one
+	// expected, i.e. that the model has changed since it was retrieved.
+	ErrVersionMismatch = errors.New("nupstore: version mismatch")
+)
+
+// Change describes a change to a model in a Store.
+type Change[K comparable, M, P any] struct {
+	// Key identifies the model.
+	Key K
+	// Version is the model's version after the change.
+	Version uint64
+	// Patch is the effective patch: the patch that was applied, less any
+	// updates that wouldn't have changed the model. It's the zero P,
+	// i.e. all no-ops, when the model is created.
+	Patch P
+	// Before is the model before the change. It's the zero M when the model
+	// is created.
+	Before M
+	// After is the model after the change.
+	After M
+}
+
+// Store holds models of type M, which must be a struct type, by keys of type K,
+// and applies patch structs of type P to them. It's safe for concurrent use.
+// The models and patches passed to and returned by a Store must be treated as
+// immutable, since they may be shared; in particular, slices and pointers must
+// not be modified in place.
+//
+// A Store must be created with NewStore.
+type Store[K comparable, M, P any] struct {
+	historyLimit int
+
+	mu          sync.RWMutex
+	entries     map[K]*entry[K, M, P]
+	subscribers []subscriber[K, M, P]
+	nextID      int
+	// sequence numbers the changes in the order in which they were made.
+	sequence uint64
+
+	// delivered is the sequence number of the last change delivered to
+	// subscribers. A change is delivered only once the previous one has been,
+	// so that subscribers are notified of changes in order, without s.mu
+	// being held while they're notified.
+	delivered uint64
+	notifyMu  sync.Mutex
+	notified  *sync.Cond
+}
+
+type subscriber[K comparable, M, P any] struct {
+	id int
+	fn func(Change[K, M, P])
+}
+
+type entry[K comparable, M, P any] struct {
+	model   M
+	version uint64
+	history []Change[K, M, P]
+}
+
+// NewStore returns an empty Store that keeps the last historyLimit changes to
+// each model. If historyLimit is zero, no history is kept.
+func NewStore[K comparable, M, P any](historyLimit int) *Store[K, M, P] {
+	s := &Store[K, M, P]{
+		historyLimit: historyLimit,
+		entries:      map[K]*entry[K, M, P]{},
+	}
+	s.notified = sync.NewCond(&s.notifyMu)
+	return s
+}
+
+// Create adds model to the store under key, with version 1. It returns
+// ErrExists if the store already holds key.
+func (s *Store[K, M, P]) Create(key K, model M) (uint64, error) {
+	s.mu.Lock()
+	if _, ok := s.entries[key]; ok {
+		s.mu.Unlock()
+		return 0, fmt.Errorf("%w: %v", ErrExists, key)
+	}
+	change := Change[K, M, P]{
+		Key:     key,
+		Version: 1,
+		After:   model,
+	}
+	e := &entry[K, M, P]{
+		model:   model,
+		version: 1,
+	}
+	e.record(change, s.historyLimit)
+	s.entries[key] = e
+	s.commit(change)
+	return 1, nil
+}
+
+// Get returns the model held under key and its version, or ErrNotFound.
+func (s *Store[K, M, P]) Get(key K) (M, uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.entries[key]
+	if !ok {
+		var zero M
+		return zero, 0, fmt.Errorf("%w: %v", ErrNotFound, key)
+	}
+	return e.model, e.version, nil
+}
+
+// Apply atomically applies patch to the model held under key, provided that
+// the model's version is version, and returns the resulting model and version.
+// It returns ErrNotFound if the store doesn't hold key, ErrVersionMismatch if
+// the model's version isn't version, or an error from nup.DiffPatch or
+// nup.ApplyPatch if patch doesn't match the model's type.
+//
+// Updates that wouldn't change the model are dropped, as by nup.DiffPatch. If
+// none remain, Apply returns the model unchanged, without incrementing its
+// version, recording a change, or notifying subscribers. Otherwise, the patch
+// is applied to a copy of the model, so that models returned earlier are
+// unaffected.
+func (s *Store[K, M, P]) Apply(key K, version uint64, patch P) (M, uint64, error) {
+	s.mu.Lock()
+	e, ok := s.entries[key]
+	if !ok {
+		s.mu.Unlock()
+		var zero M
+		return zero, 0, fmt.Errorf("%w: %v", ErrNotFound, key)
+	}
+	current, currentVersion := e.model, e.version
+	if currentVersion != version {
+		s.mu.Unlock()
+		return current, currentVersion, fmt.Errorf("%w: %v: expected version %d, found %d", ErrVersionMismatch, key, version, currentVersion)
+	}
+	changed, err := nup.DiffPatch(&patch, current)
+	if err != nil || !changed {
+		s.mu.Unlock()
+		return current, currentVersion, err
+	}
+	model := nup.CopyOnWrite(current, patch)
+	if err := nup.ApplyPatch(&model, patch); err != nil {
+		s.mu.Unlock()
+		return current, currentVersion, err
+	}
+	change := Change[K, M, P]{
+		Key:     key,
+		Version: currentVersion + 1,
+		Patch:   patch,
+		Before:  current,
+		After:   model,
+	}
+	e.model = model
+	e.version = change.Version
+	e.record(change, s.historyLimit)
+	s.commit(change)
+	return model, change.Version, nil
+}
+
+// History returns the recorded changes to the model held under key, oldest
+// first, or ErrNotFound. At most the number of changes given to NewStore are
+// recorded.
+func (s *Store[K, M, P]) History(key K) ([]Change[K, M, P], error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrNotFound, key)
+	}
+	return append([]Change[K, M, P](nil), e.history...), nil
+}
+
+// Subscribe registers fn to be called with each subsequent change to any model
+// in the store, including its creation, and returns a function that
+// unregisters it. Changes are delivered in the order in which they were made,
+// after they've been made, and fn is called synchronously by the goroutine
+// making the change. Thus fn should return quickly, and it may read from the
+// store but must not modify it.
+func (s *Store[K, M, P]) Subscribe(fn func(Change[K, M, P])) (unsubscribe func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextID
+	s.nextID++
+	s.subscribers = append(s.subscribers, subscriber[K, M, P]{id: id, fn: fn})
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i, sub := range s.subscribers {
+			if sub.id == id {
+				// Copy rather than modify the slice in place, since commit
+				// may be iterating over it.
+				s.subscribers = append(s.subscribers[:i:i], s.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// commit notifies subscribers of a change, releasing s.mu, which must be held
+// for writing. The change is numbered before s.mu is released, and delivered
+// once the changes numbered before it have been, so that changes are delivered
+// in order while subscribers remain free to read from the store.
+func (s *Store[K, M, P]) commit(change Change[K, M, P]) {
+	s.sequence++
+	sequence, subscribers := s.sequence, s.subscribers
+	s.mu.Unlock()
+
+	s.notifyMu.Lock()
+	for s.delivered != sequence-1 {
+		s.notified.Wait()
+	}
+	s.notifyMu.Unlock()
+	defer func() {
+		s.notifyMu.Lock()
+		s.delivered = sequence
+		s.notifyMu.Unlock()
+		s.notified.Broadcast()
+	}()
+	for _, sub := range subscribers {
+		sub.fn(change)
+	}
+}
+
+// record appends change to the entry's history, discarding the oldest change
+// if the history exceeds limit.
+func (e *entry[K, M, P]) record(change Change[K, M, P], limit int) {
+	if limit <= 0 {
+		return
+	}
+	if len(e.history) == limit {
+		// Copy rather than reslice, so that the discarded changes can be
+		// garbage collected and returned histories aren't overwritten.
+		e.history = append(make([]Change[K, M, P], 0, limit), e.history[1:]...)
+	}
+	e.history = append(e.history, change)
+}
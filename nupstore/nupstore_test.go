@@ -0,0 +1,320 @@
+package nupstore
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/nicheinc/expect"
+
+	"github.com/nicheinc/nullable/v2/nup"
+)
+
+type testAddress struct {
+	City string
+	Zip  *string
+}
+
+type testModel struct {
+	Name    string
+	Count   int
+	Tags    []string
+	Address *testAddress
+}
+
+type testAddressPatch struct {
+	City nup.Update[string] `json:"city"`
+	Zip  nup.Update[string] `json:"zip"`
+}
+
+type testPatch struct {
+	Name    nup.Update[string]      `json:"name"`
+	Count   nup.Update[int]         `json:"count"`
+	Tags    nup.SliceUpdate[string] `json:"tags"`
+	Address testAddressPatch        `json:"address"`
+}
+
+func newTestStore(t *testing.T, historyLimit int) *Store[string, testModel, testPatch] {
+	store := NewStore[string, testModel, testPatch](historyLimit)
+	version, err := store.Create("alice", testModel{Name: "Alice", Tags: []string{"a"}})
+	expect.ErrorNil(t, err)
+	expect.Equal(t, version, 1)
+	return store
+}
+
+func TestStore_Apply(t *testing.T) {
+	testCases := []struct {
+		name            string
+		key             string
+		version         uint64
+		patch           testPatch
+		errorCheck      expect.ErrorCheck
+		expectedModel   testModel
+		expectedVersion uint64
+	}{
+		{
+			name:    "Applied",
+			key:     "alice",
+			version: 1,
+			patch: testPatch{
+				Count:   nup.Set(1),
+				Tags:    nup.SliceRemove[string](),
+				Address: testAddressPatch{City: nup.Set("Paris")},
+			},
+			errorCheck:      expect.ErrorNil,
+			expectedModel:   testModel{Name: "Alice", Count: 1, Address: &testAddress{City: "Paris"}},
+			expectedVersion: 2,
+		},
+		{
+			name:    "NoEffectiveChanges",
+			key:     "alice",
+			version: 1,
+			patch: testPatch{
+				Name: nup.Set("Alice"),
+				Tags: nup.SliceRemoveOrSet([]string{"a"}),
+			},
+			errorCheck:      expect.ErrorNil,
+			expectedModel:   testModel{Name: "Alice", Tags: []string{"a"}},
+			expectedVersion: 1,
+		},
+		{
+			name:            "VersionMismatch",
+			key:             "alice",
+			version:         2,
+			patch:           testPatch{Count: nup.Set(1)},
+			errorCheck:      expect.ErrorIs(ErrVersionMismatch),
+			expectedModel:   testModel{Name: "Alice", Tags: []string{"a"}},
+			expectedVersion: 1,
+		},
+		{
+			name:       "NotFound",
+			key:        "bob",
+			version:    1,
+			patch:      testPatch{Count: nup.Set(1)},
+			errorCheck: expect.ErrorIs(ErrNotFound),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			store := newTestStore(t, 10)
+			model, version, err := store.Apply(testCase.key, testCase.version, testCase.patch)
+			testCase.errorCheck(t, err)
+			expect.Equal(t, model, testCase.expectedModel)
+			expect.Equal(t, version, testCase.expectedVersion)
+		})
+	}
+}
+
+func TestStore_Apply_MismatchedPatch(t *testing.T) {
+	store := NewStore[string, testModel, struct{ Count nup.Update[string] }](0)
+	_, err := store.Create("alice", testModel{})
+	expect.ErrorNil(t, err)
+	_, version, err := store.Apply("alice", 1, struct{ Count nup.Update[string] }{Count: nup.Set("1")})
+	expect.ErrorNonNil(t, err)
+	expect.Equal(t, version, 1)
+}
+
+func TestStore_Create(t *testing.T) {
+	store := newTestStore(t, 0)
+	_, err := store.Create("alice", testModel{Name: "Other"})
+	expect.ErrorIs(ErrExists)(t, err)
+
+	model, version, err := store.Get("alice")
+	expect.ErrorNil(t, err)
+	expect.Equal(t, model, testModel{Name: "Alice", Tags: []string{"a"}})
+	expect.Equal(t, version, 1)
+
+	_, _, err = store.Get("bob")
+	expect.ErrorIs(ErrNotFound)(t, err)
+}
+
+// TestStore_Apply_CopyOnWrite ensures that applying a patch doesn't modify
+// models returned earlier, including nested structs.
+func TestStore_Apply_CopyOnWrite(t *testing.T) {
+	var (
+		zip   = "75001"
+		store = NewStore[string, testModel, testPatch](0)
+	)
+	_, err := store.Create("alice", testModel{Address: &testAddress{City: "Paris", Zip: &zip}})
+	expect.ErrorNil(t, err)
+	before, _, err := store.Get("alice")
+	expect.ErrorNil(t, err)
+
+	after, _, err := store.Apply("alice", 1, testPatch{
+		Address: testAddressPatch{City: nup.Set("Lyon"), Zip: nup.Remove[string]()},
+	})
+	expect.ErrorNil(t, err)
+	expect.Equal(t, before, testModel{Address: &testAddress{City: "Paris", Zip: &zip}})
+	expect.Equal(t, after, testModel{Address: &testAddress{City: "Lyon"}})
+}
+
+func TestStore_History(t *testing.T) {
+	store := newTestStore(t, 2)
+	_, _, err := store.Apply("alice", 1, testPatch{Count: nup.Set(1)})
+	expect.ErrorNil(t, err)
+	// A patch without effective changes isn't recorded.
+	_, _, err = store.Apply("alice", 2, testPatch{Name: nup.Set("Alice"), Count: nup.Set(1)})
+	expect.ErrorNil(t, err)
+	_, _, err = store.Apply("alice", 2, testPatch{Name: nup.Set("Bob"), Count: nup.Set(1)})
+	expect.ErrorNil(t, err)
+
+	history, err := store.History("alice")
+	expect.ErrorNil(t, err)
+	// The creation has been discarded.
+	expect.Equal(t, history, []Change[string, testModel, testPatch]{
+		{
+			Key:     "alice",
+			Version: 2,
+			Patch:   testPatch{Count: nup.Set(1)},
+			Before:  testModel{Name: "Alice", Tags: []string{"a"}},
+			After:   testModel{Name: "Alice", Count: 1, Tags: []string{"a"}},
+		},
+		{
+			Key:     "alice",
+			Version: 3,
+			Patch:   testPatch{Name: nup.Set("Bob")},
+			Before:  testModel{Name: "Alice", Count: 1, Tags: []string{"a"}},
+			After:   testModel{Name: "Bob", Count: 1, Tags: []string{"a"}},
+		},
+	})
+
+	_, err = store.History("bob")
+	expect.ErrorIs(ErrNotFound)(t, err)
+
+	store = newTestStore(t, 0)
+	history, err = store.History("alice")
+	expect.ErrorNil(t, err)
+	expect.Equal(t, len(history), 0)
+}
+
+func TestStore_Subscribe(t *testing.T) {
+	var (
+		store    = NewStore[string, testModel, testPatch](0)
+		first    []uint64
+		second   []uint64
+		unsubOne = store.Subscribe(func(change Change[string, testModel, testPatch]) {
+			first = append(first, change.Version)
+		})
+	)
+	store.Subscribe(func(change Change[string, testModel, testPatch]) {
+		// Subscribers may read from the store.
+		_, version, err := store.Get(change.Key)
+		expect.ErrorNil(t, err)
+		expect.Equal(t, version, change.Version)
+		second = append(second, change.Version)
+	})
+
+	_, err := store.Create("alice", testModel{})
+	expect.ErrorNil(t, err)
+	_, _, err = store.Apply("alice", 1, testPatch{Count: nup.Set(1)})
+	expect.ErrorNil(t, err)
+	unsubOne()
+	unsubOne()
+	_, _, err = store.Apply("alice", 2, testPatch{Count: nup.Set(2)})
+	expect.ErrorNil(t, err)
+	// Ineffective and failed patches aren't delivered.
+	_, _, err = store.Apply("alice", 3, testPatch{Count: nup.Set(2)})
+	expect.ErrorNil(t, err)
+	_, _, err = store.Apply("alice", 1, testPatch{Count: nup.Set(3)})
+	expect.ErrorIs(ErrVersionMismatch)(t, err)
+
+	expect.Equal(t, first, []uint64{1, 2})
+	expect.Equal(t, second, []uint64{1, 2, 3})
+}
+
+// TestStore_Concurrent runs concurrent writers that increment a counter with
+// compare-and-swap loops, ensuring that no increment is lost and that
+// subscribers see every version in order. Run it with the race detector.
+func TestStore_Concurrent(t *testing.T) {
+	const (
+		writers    = 8
+		increments = 50
+	)
+	var (
+		store    = NewStore[string, testModel, testPatch](5)
+		versions []uint64
+		wg       sync.WaitGroup
+	)
+	_, err := store.Create("counter", testModel{Address: &testAddress{}})
+	expect.ErrorNil(t, err)
+	store.Subscribe(func(change Change[string, testModel, testPatch]) {
+		versions = append(versions, change.Version)
+	})
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < increments; j++ {
+				for {
+					model, version, err := store.Get("counter")
+					expect.ErrorNil(t, err)
+					_, _, err = store.Apply("counter", version, testPatch{
+						Count:   nup.Set(model.Count + 1),
+						Address: testAddressPatch{City: nup.Set(model.Address.City + "x")},
+					})
+					if !errors.Is(err, ErrVersionMismatch) {
+						expect.ErrorNil(t, err)
+						break
+					}
+				}
+				_, err := store.History("counter")
+				expect.ErrorNil(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	model, version, err := store.Get("counter")
+	expect.ErrorNil(t, err)
+	expect.Equal(t, model.Count, writers*increments)
+	expect.Equal(t, len(model.Address.City), writers*increments)
+	expect.Equal(t, version, writers*increments+1)
+	expect.Equal(t, len(versions), writers*increments)
+	for i, v := range versions {
+		expect.Equal(t, v, uint64(i+2))
+	}
+	history, err := store.History("counter")
+	expect.ErrorNil(t, err)
+	expect.Equal(t, len(history), 5)
+}
+
+// TestStore_Subscribe_Concurrent ensures that subscribers may read from the
+// store while other goroutines write to it, and still see every change in
+// order. Run it with the race detector.
+func TestStore_Subscribe_Concurrent(t *testing.T) {
+	const (
+		writers    = 16
+		increments = 200
+	)
+	var (
+		store    = NewStore[string, testModel, testPatch](0)
+		versions []uint64
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < writers; i++ {
+		_, err := store.Create(fmt.Sprint(i), testModel{})
+		expect.ErrorNil(t, err)
+	}
+	store.Subscribe(func(change Change[string, testModel, testPatch]) {
+		_, version, err := store.Get(change.Key)
+		expect.ErrorNil(t, err)
+		if version < change.Version {
+			t.Errorf("got version %d of %s after change to version %d", version, change.Key, change.Version)
+		}
+		versions = append(versions, change.Version)
+	})
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			for version := uint64(1); version <= increments; version++ {
+				_, _, err := store.Apply(key, version, testPatch{Count: nup.Set(int(version))})
+				expect.ErrorNil(t, err)
+			}
+		}(fmt.Sprint(i))
+	}
+	wg.Wait()
+	expect.Equal(t, len(versions), writers*increments)
+}
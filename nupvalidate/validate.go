@@ -0,0 +1,328 @@
+/*
+Package nupvalidate validates patch structs: structs whose fields are nup.Update
+or nup.SliceUpdate values. Only the changes a patch actually makes are checked,
+so a no-op never fails validation, a removal fails only if it's disallowed, and
+the constraints on values apply only to set operations.
+
+Constraints are declared with "nupvalidate" struct tags, for example:
+
+	type UserPatch struct {
+		Name  nup.Update[string]      `json:"name" nupvalidate:"noremove,minlen=1,maxlen=100"`
+		Age   nup.Update[int]         `json:"age" nupvalidate:"min=0,max=150"`
+		Email nup.Update[string]      `json:"email" nupvalidate:"pattern=^[^@]+@[^@]+$"`
+		Tags  nup.SliceUpdate[string] `json:"tags" nupvalidate:"maxlen=10"`
+	}
+
+The supported options are:
+  - noremove: the field may not be removed.
+  - min=N, max=N: a set value, which must be numeric, must be at least or at
+    most N.
+  - minlen=N, maxlen=N: a set value, which must be a string or slice, must have
+    a length (in runes, for strings) of at least or at most N.
+  - pattern=RE: a set value, which must be a string, must match the regular
+    expression RE. Since RE may contain commas, pattern must be the last option
+    in the tag.
+
+In addition, if a set value (or, for a SliceUpdate, each of its elements)
+implements Validator, its Validate method is called.
+*/
+package nupvalidate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/nicheinc/nullable/v2/nup"
+)
+
+var (
+	// ErrNoRemove indicates the removal of a field marked noremove.
+	ErrNoRemove = errors.New("removal not allowed")
+	// ErrMin indicates a value less than a field's min.
+	ErrMin = errors.New("must be at least")
+	// ErrMax indicates a value greater than a field's max.
+	ErrMax = errors.New("must be at most")
+	// ErrMinLen indicates a value shorter than a field's minlen.
+	ErrMinLen = errors.New("length must be at least")
+	// ErrMaxLen indicates a value longer than a field's maxlen.
+	ErrMaxLen = errors.New("length must be at most")
+	// ErrPattern indicates a value that doesn't match a field's pattern.
+	ErrPattern = errors.New("must match pattern")
+)
+
+// Validator is implemented by types that can validate themselves.
+type Validator interface {
+	Validate() error
+}
+
+// Validate checks each change made by patch, a patch struct or a pointer to
+// one, against the constraints in its fields' "nupvalidate" struct tags and
+// against the Validate methods of its set values. It returns all the problems
+// it finds as a nup.FieldErrors, each identifying the offending field by its
+// JSON pointer.
+//
+// Validate returns an error that is not a nup.FieldErrors if a struct tag is
+// malformed, whether or not patch changes its field, or specifies a constraint
+// that doesn't apply to its field's type. Tags are parsed once per patch type.
+func Validate(patch interface{}) error {
+	var (
+		errs   nup.FieldErrors
+		fields = nup.Fields(patch)
+	)
+	fieldRules, err := patchRules(reflect.TypeOf(patch), fields)
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		if field.Operation == nup.OpNoop {
+			continue
+		}
+		rules := fieldRules[field.Name]
+		fieldError := func(path string, err error) *nup.FieldError {
+			return &nup.FieldError{
+				Path:  path,
+				Field: field.Name,
+				Op:    field.Operation,
+				Err:   err,
+			}
+		}
+		if field.Operation == nup.OpRemove {
+			if rules.noRemove {
+				errs = append(errs, fieldError(field.Path, ErrNoRemove))
+			}
+			continue
+		}
+		value := reflect.ValueOf(field.Value)
+		if !value.IsValid() {
+			// The update sets a nil interface value.
+			continue
+		}
+		for _, check := range rules.checks {
+			err := check(value)
+			if errors.Is(err, errNotApplicable) {
+				return fmt.Errorf("nupvalidate: field %s: constraint doesn't apply to %s", field.Name, value.Type())
+			}
+			if err != nil {
+				errs = append(errs, fieldError(field.Path, err))
+			}
+		}
+		// Call Validate on the value itself or, for a SliceUpdate, on each
+		// element. (An Update's value can't be a slice, which isn't
+		// comparable.)
+		if value.Kind() == reflect.Slice {
+			for i := 0; i < value.Len(); i++ {
+				if err := validate(value.Index(i)); err != nil {
+					errs = append(errs, fieldError(field.Path+"/"+strconv.Itoa(i), err))
+				}
+			}
+		} else if err := validate(value); err != nil {
+			errs = append(errs, fieldError(field.Path, err))
+		}
+	}
+	return errs.Err()
+}
+
+// validate calls Validate on v if v or a pointer to v implements Validator.
+func validate(v reflect.Value) error {
+	if validator, ok := v.Interface().(Validator); ok {
+		return validator.Validate()
+	}
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	if validator, ok := ptr.Interface().(Validator); ok {
+		return validator.Validate()
+	}
+	return nil
+}
+
+// rulesCache maps patch struct types, or pointers to them, to their
+// typeRules.
+var rulesCache sync.Map
+
+// typeRules holds the rules of the fields of a patch struct type, by qualified
+// field name, or the error from parsing them.
+type typeRules struct {
+	fields map[string]rules
+	err    error
+}
+
+// patchRules returns the rules of the fields of the patch struct type t, given
+// its fields, parsing their tags the first time it's called for t, so that a
+// malformed tag is reported whichever fields a patch changes.
+func patchRules(t reflect.Type, fields []nup.Field) (map[string]rules, error) {
+	if cached, ok := rulesCache.Load(t); ok {
+		return cached.(typeRules).fields, cached.(typeRules).err
+	}
+	result := typeRules{fields: make(map[string]rules, len(fields))}
+	for _, field := range fields {
+		rules, err := parseRules(field.StructField.Tag.Get("nupvalidate"))
+		if err != nil {
+			result = typeRules{err: fmt.Errorf("nupvalidate: field %s: %w", field.Name, err)}
+			break
+		}
+		result.fields[field.Name] = rules
+	}
+	if fields != nil {
+		// Fields returns nil for a nil pointer, whose rules are incomplete.
+		rulesCache.Store(t, result)
+	}
+	return result.fields, result.err
+}
+
+// rules holds the constraints parsed from a "nupvalidate" struct tag.
+type rules struct {
+	noRemove bool
+	checks   []check
+}
+
+// check validates a set value. It returns errNotApplicable if the check
+// doesn't apply to the value's type.
+type check func(reflect.Value) error
+
+var errNotApplicable = errors.New("not applicable")
+
+func parseRules(tag string) (rules, error) {
+	var rules rules
+	for tag != "" {
+		var opt string
+		if strings.HasPrefix(tag, "pattern=") {
+			// The pattern consumes the rest of the tag.
+			opt, tag = tag, ""
+		} else {
+			opt, tag, _ = strings.Cut(tag, ",")
+		}
+		name, arg, hasArg := strings.Cut(opt, "=")
+		if name == "noremove" && !hasArg {
+			rules.noRemove = true
+			continue
+		}
+		if !hasArg {
+			return rules, fmt.Errorf("unknown option %q", opt)
+		}
+		var (
+			check check
+			err   error
+		)
+		switch name {
+		case "min":
+			check, err = compareCheck(arg, ErrMin, -1)
+		case "max":
+			check, err = compareCheck(arg, ErrMax, 1)
+		case "minlen":
+			check, err = lenCheck(arg, ErrMinLen, func(length, limit int) bool { return length >= limit })
+		case "maxlen":
+			check, err = lenCheck(arg, ErrMaxLen, func(length, limit int) bool { return length <= limit })
+		case "pattern":
+			check, err = patternCheck(arg)
+		default:
+			err = fmt.Errorf("unknown option %q", opt)
+		}
+		if err != nil {
+			return rules, err
+		}
+		rules.checks = append(rules.checks, check)
+	}
+	return rules, nil
+}
+
+// compareCheck returns a check that fails with errBound if comparing a numeric
+// value to the limit yields the given sign (-1 for less, 1 for greater).
+func compareCheck(limit string, errBound error, sign int) (check, error) {
+	// Parse the limit for each kind of number up front. Any valid limit
+	// parses as a float.
+	var (
+		intLimit, intErr     = strconv.ParseInt(limit, 10, 64)
+		uintLimit, uintErr   = strconv.ParseUint(limit, 10, 64)
+		floatLimit, floatErr = strconv.ParseFloat(limit, 64)
+	)
+	if floatErr != nil {
+		return nil, fmt.Errorf("invalid limit %q", limit)
+	}
+	return func(v reflect.Value) error {
+		var cmp int
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if intErr != nil {
+				return errNotApplicable
+			}
+			cmp = compare(v.Int(), intLimit)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			switch {
+			case uintErr == nil:
+				cmp = compare(v.Uint(), uintLimit)
+			case intErr == nil:
+				// The limit is negative, so every value is greater.
+				cmp = 1
+			default:
+				return errNotApplicable
+			}
+		case reflect.Float32, reflect.Float64:
+			cmp = compare(v.Float(), floatLimit)
+		default:
+			return errNotApplicable
+		}
+		if cmp == sign {
+			return fmt.Errorf("%w %s", errBound, limit)
+		}
+		return nil
+	}, nil
+}
+
+func compare[T int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// lenCheck returns a check that fails with errBound unless ok returns true for
+// the length of a string or slice value and the limit.
+func lenCheck(limit string, errBound error, ok func(length, limit int) bool) (check, error) {
+	n, err := strconv.Atoi(limit)
+	if err != nil {
+		return nil, fmt.Errorf("invalid length %q", limit)
+	}
+	return func(v reflect.Value) error {
+		var length int
+		switch v.Kind() {
+		case reflect.String:
+			length = utf8.RuneCountInString(v.String())
+		case reflect.Slice, reflect.Array, reflect.Map:
+			length = v.Len()
+		default:
+			return errNotApplicable
+		}
+		if !ok(length, n) {
+			return fmt.Errorf("%w %d", errBound, n)
+		}
+		return nil
+	}, nil
+}
+
+// patternCheck returns a check that fails with ErrPattern unless a string
+// value matches the regular expression pattern.
+func patternCheck(pattern string) (check, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return func(v reflect.Value) error {
+		if v.Kind() != reflect.String {
+			return errNotApplicable
+		}
+		if !re.MatchString(v.String()) {
+			return fmt.Errorf("%w %s", ErrPattern, pattern)
+		}
+		return nil
+	}, nil
+}
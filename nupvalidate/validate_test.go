@@ -0,0 +1,250 @@
+package nupvalidate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/nicheinc/expect"
+
+	"github.com/nicheinc/nullable/v2/nup"
+)
+
+type color string
+
+func (c color) Validate() error {
+	switch c {
+	case "red", "green", "blue":
+		return nil
+	}
+	return errTestColor
+}
+
+var errTestColor = errors.New("unknown color")
+
+type testPatch struct {
+	Name   nup.Update[string]     `json:"name" nupvalidate:"noremove,minlen=1,maxlen=5"`
+	Age    nup.Update[int]        `json:"age" nupvalidate:"min=0,max=150"`
+	Count  nup.Update[uint]       `json:"count" nupvalidate:"min=-1,max=10"`
+	Ratio  nup.Update[float64]    `json:"ratio" nupvalidate:"min=0.5"`
+	Code   nup.Update[string]     `json:"code" nupvalidate:"pattern=^[a-z]{2,3}$"`
+	Color  nup.Update[color]      `json:"color"`
+	Colors nup.SliceUpdate[color] `json:"colors" nupvalidate:"maxlen=2"`
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name       string
+		patch      testPatch
+		errorCheck expect.ErrorCheck
+	}{
+		{
+			name:       "Noop",
+			patch:      testPatch{},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "Valid",
+			patch: testPatch{
+				Name:   nup.Set("Alice"),
+				Age:    nup.Set(0),
+				Count:  nup.Set[uint](10),
+				Ratio:  nup.Set(0.5),
+				Code:   nup.Set("abc"),
+				Color:  nup.Set[color]("red"),
+				Colors: nup.SliceRemoveOrSet([]color{"green", "blue"}),
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "RemovalsAllowed",
+			patch: testPatch{
+				Age:    nup.Remove[int](),
+				Code:   nup.Remove[string](),
+				Colors: nup.SliceRemove[color](),
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "NoRemove",
+			patch: testPatch{
+				Name: nup.Remove[string](),
+			},
+			errorCheck: expect.ErrorIs(ErrNoRemove),
+		},
+		{
+			name: "MinLen",
+			patch: testPatch{
+				Name: nup.Set(""),
+			},
+			errorCheck: expect.ErrorIs(ErrMinLen),
+		},
+		{
+			name: "MaxLenRunes",
+			patch: testPatch{
+				Name: nup.Set("Zoë"),
+			},
+			errorCheck: expect.ErrorNil,
+		},
+		{
+			name: "MaxLen",
+			patch: testPatch{
+				Name: nup.Set("Alexander"),
+			},
+			errorCheck: expect.ErrorIs(ErrMaxLen),
+		},
+		{
+			name: "Min",
+			patch: testPatch{
+				Age: nup.Set(-1),
+			},
+			errorCheck: expect.ErrorIs(ErrMin),
+		},
+		{
+			name: "Max",
+			patch: testPatch{
+				Age: nup.Set(151),
+			},
+			errorCheck: expect.ErrorIs(ErrMax),
+		},
+		{
+			name: "MaxUint",
+			patch: testPatch{
+				Count: nup.Set[uint](11),
+			},
+			errorCheck: expect.ErrorIs(ErrMax),
+		},
+		{
+			name: "MinFloat",
+			patch: testPatch{
+				Ratio: nup.Set(0.25),
+			},
+			errorCheck: expect.ErrorIs(ErrMin),
+		},
+		{
+			name: "Pattern",
+			patch: testPatch{
+				Code: nup.Set("ABC"),
+			},
+			errorCheck: expect.ErrorIs(ErrPattern),
+		},
+		{
+			name: "Validator",
+			patch: testPatch{
+				Color: nup.Set[color]("pink"),
+			},
+			errorCheck: expect.ErrorIs(errTestColor),
+		},
+		{
+			name: "SliceMaxLen",
+			patch: testPatch{
+				Colors: nup.SliceRemoveOrSet([]color{"red", "green", "blue"}),
+			},
+			errorCheck: expect.ErrorIs(ErrMaxLen),
+		},
+		{
+			name: "SliceValidator",
+			patch: testPatch{
+				Colors: nup.SliceRemoveOrSet([]color{"red", "pink"}),
+			},
+			errorCheck: expect.ErrorIs(errTestColor),
+		},
+		{
+			name: "Multiple",
+			patch: testPatch{
+				Name: nup.Remove[string](),
+				Age:  nup.Set(200),
+				Code: nup.Set("?"),
+			},
+			errorCheck: expect.ErrorIsAll(ErrNoRemove, ErrMax, ErrPattern),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := Validate(&testCase.patch)
+			testCase.errorCheck(t, err)
+		})
+	}
+}
+
+func TestValidate_FieldErrors(t *testing.T) {
+	patch := testPatch{
+		Name:   nup.Remove[string](),
+		Colors: nup.SliceRemoveOrSet([]color{"red", "pink"}),
+	}
+	err := Validate(patch)
+	var errs nup.FieldErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected nup.FieldErrors, got %T", err)
+	}
+	expect.Equal(t, len(errs), 2)
+	expect.Equal(t, *errs[0], nup.FieldError{
+		Path:  "/name",
+		Field: "Name",
+		Op:    nup.OpRemove,
+		Err:   ErrNoRemove,
+	}, cmpopts.EquateErrors())
+	expect.Equal(t, errs[1].Path, "/colors/1")
+	expect.Equal(t, errs[1].Op, nup.OpSet)
+}
+
+func TestValidate_InvalidTag(t *testing.T) {
+	testCases := []struct {
+		name  string
+		patch interface{}
+	}{
+		{
+			name: "UnknownOption",
+			patch: struct {
+				Name nup.Update[string] `nupvalidate:"bogus"`
+			}{Name: nup.Set("")},
+		},
+		{
+			name: "UnknownOptionNoop",
+			patch: struct {
+				Name nup.Update[string] `nupvalidate:"bogus"`
+				Age  nup.Update[int]
+			}{Age: nup.Set(1)},
+		},
+		{
+			name: "InvalidPatternNoop",
+			patch: &struct {
+				Code nup.Update[string] `nupvalidate:"pattern=("`
+			}{},
+		},
+		{
+			name: "InvalidLimit",
+			patch: struct {
+				Age nup.Update[int] `nupvalidate:"min=zero"`
+			}{Age: nup.Set(0)},
+		},
+		{
+			name: "FractionalLimitForInt",
+			patch: struct {
+				Age nup.Update[int] `nupvalidate:"min=0.5"`
+			}{Age: nup.Set(0)},
+		},
+		{
+			name: "InvalidPattern",
+			patch: struct {
+				Code nup.Update[string] `nupvalidate:"pattern=("`
+			}{Code: nup.Set("")},
+		},
+		{
+			name: "WrongType",
+			patch: struct {
+				Flag nup.Update[bool] `nupvalidate:"max=1"`
+			}{Flag: nup.Set(true)},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := Validate(testCase.patch)
+			expect.ErrorNonNil(t, err)
+			var errs nup.FieldErrors
+			expect.Equal(t, errors.As(err, &errs), false)
+		})
+	}
+}
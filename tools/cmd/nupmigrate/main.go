@@ -0,0 +1,24 @@
+// Command nupmigrate migrates code from version 1 of the nullable package
+// (github.com/nicheinc/nullable) to nup. See
+// https://pkg.go.dev/github.com/nicheinc/nullable/v2/tools/nupmigrate for the
+// rewrites it performs.
+//
+// Usage:
+//
+//	nupmigrate packages...             # report what would change
+//	nupmigrate -fix -diff packages...  # print the changes as a diff
+//	nupmigrate -fix packages...        # rewrite files in place
+//
+// Statements that need a human decision, such as calls to IsSet, are marked
+// with "TODO(nupmigrate)" comments.
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/nicheinc/nullable/v2/tools/nupmigrate"
+)
+
+func main() {
+	singlechecker.Main(nupmigrate.Analyzer)
+}
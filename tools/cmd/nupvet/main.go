@@ -0,0 +1,17 @@
+// Command nupvet reports likely misuses of the nup package. See
+// https://pkg.go.dev/github.com/nicheinc/nullable/v2/tools/nupvet for details.
+//
+// Usage:
+//
+//	nupvet [-fix] [-diff] packages...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/nicheinc/nullable/v2/tools/nupvet"
+)
+
+func main() {
+	singlechecker.Main(nupvet.Analyzer)
+}
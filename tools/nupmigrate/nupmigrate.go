@@ -0,0 +1,526 @@
+/*
+Package nupmigrate defines an analyzer that rewrites code using version 1 of
+the nullable package (github.com/nicheinc/nullable) to use version 2
+(github.com/nicheinc/nullable/v2/nup), following the table in the migration
+guide:
+
+	nullable.Int           →  nup.Update[int]
+	nullable.NewInt(v)     →  nup.Set(v)
+	nullable.NewIntPtr(p)  →  nup.RemoveOrSet(p)
+	u.Value()              →  u.ValueOrNil()
+	u.Removed()            →  u.IsRemove()
+	u.Equals(5)            →  u.IsSetTo(5)
+	u.IsNegative()         →  u.IsSetSuchThat(func(v int) bool { return v < 0 })
+	u.IsZero()             →  u.IsSetTo(0)
+	u.IsEmpty()            →  u.IsSetTo("")
+
+Slice types like nullable.StringSlice become nup.SliceUpdate[string], and the
+v1 import is replaced with the nup import, along with imports of any packages
+that the rewritten code newly refers to, e.g. "time" for nup.Update[time.Time].
+
+The analyzer derives each v1 type's update type from the return type of its
+Value method, so it handles every v1 type, not just the ones listed above.
+
+Some rewrites can't be done mechanically. The meaning of IsSet changed from
+"set or removed" to "set only", so the analyzer leaves a TODO comment above
+each statement calling IsSet on a v1 type rather than guessing whether IsChange
+was meant. Slice constructors and slice Value calls, whose nil handling differs
+between versions, are flagged the same way. Uses of the v1 package that have no
+version 2 equivalent are reported without a fix.
+*/
+package nupmigrate
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const (
+	v1Path = "github.com/nicheinc/nullable"
+	v2Path = "github.com/nicheinc/nullable/v2/nup"
+)
+
+// Analyzer reports uses of nullable v1 and suggests fixes that migrate them to
+// nup.
+var Analyzer = &analysis.Analyzer{
+	Name:     "nupmigrate",
+	Doc:      "migrate uses of github.com/nicheinc/nullable to github.com/nicheinc/nullable/v2/nup",
+	URL:      "https://pkg.go.dev/github.com/nicheinc/nullable/v2/tools/nupmigrate",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// renamedMethods maps v1 method names to their version 2 equivalents.
+var renamedMethods = map[string]string{
+	"Removed": "IsRemove",
+	"Equals":  "IsSetTo",
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	m := migrator{
+		pass:      pass,
+		todoStmts: map[ast.Stmt]bool{},
+		imports:   map[*ast.File]*fileImports{},
+	}
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	filter := []ast.Node{
+		(*ast.ImportSpec)(nil),
+		(*ast.SelectorExpr)(nil),
+	}
+	for cursor := range inspect.Root().Preorder(filter...) {
+		switch node := cursor.Node().(type) {
+		case *ast.ImportSpec:
+			m.migrateImport(cursor, node)
+		case *ast.SelectorExpr:
+			m.migrateSelector(cursor, node)
+		}
+	}
+	// Report the imports last, once the packages that the other fixes
+	// refer to are known.
+	for _, file := range pass.Files {
+		if imports, ok := m.imports[file]; ok {
+			m.reportImports(file, imports)
+		}
+	}
+	return nil, nil
+}
+
+type migrator struct {
+	pass *analysis.Pass
+	// todoStmts records the statements that already have a TODO comment, so
+	// that multiple problems in one statement produce just one comment.
+	todoStmts map[ast.Stmt]bool
+	// imports records, for each file, its v1 import and the packages that
+	// the suggested fixes refer to but the file doesn't import.
+	imports map[*ast.File]*fileImports
+}
+
+// fileImports describes the imports of a file that need to be migrated.
+type fileImports struct {
+	// v1 is the file's import of the v1 package, if any.
+	v1 *ast.ImportSpec
+	// missing holds the paths of packages to be imported.
+	missing map[string]bool
+}
+
+// update describes the nup type corresponding to a v1 type.
+type update struct {
+	// slice indicates a SliceUpdate rather than an Update.
+	slice bool
+	// elem is the element type: T in Update[T] or SliceUpdate[T].
+	elem types.Type
+}
+
+// typeName returns the nup type's name, as written in the given file.
+func (u update) typeName(qualifier types.Qualifier) string {
+	name := "Update"
+	if u.slice {
+		name = "SliceUpdate"
+	}
+	return fmt.Sprintf("nup.%s[%s]", name, types.TypeString(u.elem, qualifier))
+}
+
+// v1Update returns the nup type corresponding to the v1 type t, based on the
+// return type of t's Value method, which is *T for value types and *[]T for
+// slice types.
+func v1Update(t types.Type) (update, bool) {
+	if ptr, ok := types.Unalias(t).(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != v1Path {
+		return update{}, false
+	}
+	obj, _, _ := types.LookupFieldOrMethod(named, true, named.Obj().Pkg(), "Value")
+	method, ok := obj.(*types.Func)
+	if !ok {
+		return update{}, false
+	}
+	results := method.Signature().Results()
+	if results.Len() != 1 {
+		return update{}, false
+	}
+	ptr, ok := results.At(0).Type().(*types.Pointer)
+	if !ok {
+		return update{}, false
+	}
+	if slice, ok := ptr.Elem().(*types.Slice); ok {
+		return update{slice: true, elem: slice.Elem()}, true
+	}
+	return update{elem: ptr.Elem()}, true
+}
+
+// migrateImport records an import of the v1 package, to be reported by
+// reportImports.
+func (m *migrator) migrateImport(cursor inspector.Cursor, spec *ast.ImportSpec) {
+	if strings.Trim(spec.Path.Value, "`\"") != v1Path {
+		return
+	}
+	m.fileImports(cursor).v1 = spec
+}
+
+// reportImports reports the v1 import of a file, if any, suggesting that it be
+// replaced with the nup import and imports of any missing packages. If the file
+// doesn't import the v1 package but is missing imports nonetheless, e.g. for
+// the element type of a rewritten method call, the missing imports are
+// suggested separately.
+func (m *migrator) reportImports(file *ast.File, imports *fileImports) {
+	missing := make([]string, 0, len(imports.missing))
+	for path := range imports.missing {
+		missing = append(missing, path)
+	}
+	sort.Strings(missing)
+	spec := imports.v1
+	if spec == nil {
+		if len(missing) == 0 {
+			return
+		}
+		var text strings.Builder
+		for _, path := range missing {
+			text.WriteString("\n\nimport \"" + path + "\"")
+		}
+		m.pass.Report(analysis.Diagnostic{
+			Pos:     file.Name.Pos(),
+			End:     file.Name.End(),
+			Message: "migrating to nup requires importing " + strings.Join(missing, ", "),
+			SuggestedFixes: []analysis.SuggestedFix{{
+				Message: "Add imports",
+				TextEdits: []analysis.TextEdit{{
+					Pos:     file.Name.End(),
+					End:     file.Name.End(),
+					NewText: []byte(text.String()),
+				}},
+			}},
+		})
+		return
+	}
+	text := `"` + v2Path + `"`
+	if len(missing) > 0 {
+		// Within an import block, the imports are added alongside the
+		// nup import. Otherwise, the import declaration becomes a block.
+		grouped := slices.ContainsFunc(file.Decls, func(decl ast.Decl) bool {
+			gen, ok := decl.(*ast.GenDecl)
+			return ok && gen.Lparen.IsValid() && gen.Lparen < spec.Pos() && spec.End() < gen.Rparen
+		})
+		var builder strings.Builder
+		if !grouped {
+			builder.WriteString("(\n\t")
+		}
+		builder.WriteString(text)
+		for _, path := range missing {
+			builder.WriteString("\n\t\"" + path + "\"")
+		}
+		if !grouped {
+			builder.WriteString("\n)")
+		}
+		text = builder.String()
+	}
+	m.pass.Report(analysis.Diagnostic{
+		Pos:     spec.Pos(),
+		End:     spec.End(),
+		Message: "import of nullable v1",
+		SuggestedFixes: []analysis.SuggestedFix{{
+			Message: "Import nup",
+			TextEdits: []analysis.TextEdit{{
+				Pos:     spec.Pos(),
+				End:     spec.End(),
+				NewText: []byte(text),
+			}},
+		}},
+	})
+}
+
+// fileImports returns the record of the imports of the file enclosing cursor.
+func (m *migrator) fileImports(cursor inspector.Cursor) *fileImports {
+	file := enclosingFile(cursor)
+	imports, ok := m.imports[file]
+	if !ok {
+		imports = &fileImports{missing: map[string]bool{}}
+		m.imports[file] = imports
+	}
+	return imports
+}
+
+func (m *migrator) migrateSelector(cursor inspector.Cursor, selector *ast.SelectorExpr) {
+	// Qualified identifiers: nullable.Int, nullable.NewInt, etc.
+	if ident, ok := selector.X.(*ast.Ident); ok {
+		if pkgName, ok := m.pass.TypesInfo.Uses[ident].(*types.PkgName); ok {
+			if pkgName.Imported().Path() == v1Path {
+				m.migrateQualified(cursor, selector)
+			}
+			return
+		}
+	}
+	// Method calls on v1 types.
+	selection, ok := m.pass.TypesInfo.Selections[selector]
+	if !ok || selection.Kind() != types.MethodVal {
+		return
+	}
+	if update, ok := v1Update(selection.Recv()); ok {
+		m.migrateMethod(cursor, selector, update)
+	}
+}
+
+// migrateQualified rewrites a reference to a v1 package-level type or function.
+func (m *migrator) migrateQualified(cursor inspector.Cursor, selector *ast.SelectorExpr) {
+	qualifier := m.qualifier(cursor)
+	switch obj := m.pass.TypesInfo.Uses[selector.Sel].(type) {
+	case *types.TypeName:
+		if update, ok := v1Update(obj.Type()); ok {
+			m.replace(selector, "nullable."+obj.Name()+" is now "+update.typeName(qualifier), update.typeName(qualifier))
+			return
+		}
+	case *types.Func:
+		if obj.Name() == "MarshalJSON" {
+			m.replace(selector, "nullable.MarshalJSON is now nup.MarshalJSON", "nup.MarshalJSON")
+			return
+		}
+		params := obj.Signature().Params()
+		results := obj.Signature().Results()
+		if params.Len() != 1 || results.Len() != 1 {
+			break
+		}
+		update, ok := v1Update(results.At(0).Type())
+		if !ok {
+			break
+		}
+		param := params.At(0).Type()
+		switch {
+		case update.slice:
+			if types.Identical(param, types.NewSlice(update.elem)) {
+				m.replace(selector, "nullable."+obj.Name()+" is now nup.SliceRemoveOrSet", "nup.SliceRemoveOrSet")
+				m.todo(cursor, "nup.SliceRemoveOrSet removes when given a nil slice; check that "+obj.Name()+"'s argument is never nil or that removal is intended.")
+				return
+			}
+		case types.Identical(param, update.elem):
+			m.replace(selector, "nullable."+obj.Name()+" is now nup.Set", "nup.Set")
+			return
+		case types.Identical(param, types.NewPointer(update.elem)):
+			m.replace(selector, "nullable."+obj.Name()+" is now nup.RemoveOrSet", "nup.RemoveOrSet")
+			return
+		}
+	}
+	m.pass.Report(analysis.Diagnostic{
+		Pos:     selector.Pos(),
+		End:     selector.End(),
+		Message: "nullable." + selector.Sel.Name + " has no nup equivalent and must be migrated by hand",
+	})
+}
+
+// migrateMethod rewrites a method call on a v1 type.
+func (m *migrator) migrateMethod(cursor inspector.Cursor, selector *ast.SelectorExpr, update update) {
+	var (
+		name = selector.Sel.Name
+		elem = func() string {
+			return types.TypeString(update.elem, m.qualifier(cursor))
+		}
+	)
+	if newName, ok := renamedMethods[name]; ok {
+		m.replaceSel(selector, name+" is now "+newName, newName)
+		return
+	}
+	switch name {
+	case "Value":
+		if update.slice {
+			m.replaceSel(selector, "Value is now ValueOrNil", "ValueOrNil")
+			elem := types.TypeString(update.elem, m.names(cursor))
+			m.todo(cursor, "ValueOrNil returns []"+elem+" rather than *[]"+elem+"; update uses of the result.")
+			return
+		}
+		m.replaceSel(selector, "Value is now ValueOrNil", "ValueOrNil")
+	case "IsSet":
+		m.todo(cursor, "IsSet no longer reports removals; use IsChange if they should be included.")
+	case "IsNegative":
+		m.replaceCall(cursor, selector, "IsNegative is now IsSetSuchThat",
+			fmt.Sprintf("IsSetSuchThat(func(v %s) bool { return v < 0 })", elem()))
+	case "IsZero", "IsEmpty":
+		// In version 2, IsZero reports whether the update is a no-op, so it
+		// must be rewritten rather than left in place.
+		var replacement string
+		switch {
+		case update.slice:
+			replacement = fmt.Sprintf("IsSetSuchThat(func(v []%s) bool { return len(v) == 0 })", elem())
+		case isString(update.elem):
+			replacement = `IsSetTo("")`
+		case isNumeric(update.elem):
+			replacement = "IsSetTo(0)"
+		default:
+			m.pass.Report(analysis.Diagnostic{
+				Pos:     selector.Sel.Pos(),
+				End:     selector.Sel.End(),
+				Message: name + " has no nup equivalent and must be migrated by hand",
+			})
+			return
+		}
+		m.replaceCall(cursor, selector, name+" is now "+replacement, replacement)
+	}
+}
+
+// qualifier returns a types.Qualifier that names packages as they're imported
+// in the file enclosing cursor. Packages that the file doesn't import are
+// recorded, so that reportImports can add them; the qualifier must therefore
+// only be used for text that ends up in the code. Messages and comments should
+// use names instead.
+func (m *migrator) qualifier(cursor inspector.Cursor) types.Qualifier {
+	var (
+		file    = enclosingFile(cursor)
+		names   = m.names(cursor)
+		imports = m.fileImports(cursor)
+	)
+	return func(pkg *types.Package) string {
+		if pkg != m.pass.Pkg && !slices.ContainsFunc(file.Imports, func(spec *ast.ImportSpec) bool {
+			return strings.Trim(spec.Path.Value, "`\"") == pkg.Path()
+		}) {
+			imports.missing[pkg.Path()] = true
+		}
+		return names(pkg)
+	}
+}
+
+// names returns a types.Qualifier that names packages as they're imported in
+// the file enclosing cursor, or by their package names if they aren't.
+func (m *migrator) names(cursor inspector.Cursor) types.Qualifier {
+	file := enclosingFile(cursor)
+	return func(pkg *types.Package) string {
+		if pkg == m.pass.Pkg {
+			return ""
+		}
+		for _, spec := range file.Imports {
+			if strings.Trim(spec.Path.Value, "`\"") == pkg.Path() && spec.Name != nil {
+				return spec.Name.Name
+			}
+		}
+		return pkg.Name()
+	}
+}
+
+// enclosingFile returns the file enclosing cursor.
+func enclosingFile(cursor inspector.Cursor) *ast.File {
+	var file *ast.File
+	for c := range cursor.Enclosing((*ast.File)(nil)) {
+		file = c.Node().(*ast.File)
+	}
+	return file
+}
+
+// replace reports node, suggesting that it be replaced with newText.
+func (m *migrator) replace(node ast.Node, message, newText string) {
+	m.pass.Report(analysis.Diagnostic{
+		Pos:     node.Pos(),
+		End:     node.End(),
+		Message: message,
+		SuggestedFixes: []analysis.SuggestedFix{{
+			Message: "Migrate to nup",
+			TextEdits: []analysis.TextEdit{{
+				Pos:     node.Pos(),
+				End:     node.End(),
+				NewText: []byte(newText),
+			}},
+		}},
+	})
+}
+
+// replaceSel reports a method selector, suggesting that the method be renamed.
+func (m *migrator) replaceSel(selector *ast.SelectorExpr, message, newName string) {
+	m.replace(selector.Sel, message, newName)
+}
+
+// replaceCall reports a method call, suggesting that everything from the
+// method name to the end of the call be replaced with newText.
+func (m *migrator) replaceCall(cursor inspector.Cursor, selector *ast.SelectorExpr, message, newText string) {
+	call, ok := cursor.Parent().Node().(*ast.CallExpr)
+	if !ok || call.Fun != selector {
+		m.pass.Report(analysis.Diagnostic{
+			Pos:     selector.Sel.Pos(),
+			End:     selector.Sel.End(),
+			Message: selector.Sel.Name + " must be migrated by hand when not called directly",
+		})
+		return
+	}
+	m.pass.Report(analysis.Diagnostic{
+		Pos:     selector.Sel.Pos(),
+		End:     call.End(),
+		Message: message,
+		SuggestedFixes: []analysis.SuggestedFix{{
+			Message: "Migrate to nup",
+			TextEdits: []analysis.TextEdit{{
+				Pos:     selector.Sel.Pos(),
+				End:     call.End(),
+				NewText: []byte(newText),
+			}},
+		}},
+	})
+}
+
+// todo reports the statement enclosing cursor, suggesting a TODO comment above
+// it for issues that need a human decision.
+func (m *migrator) todo(cursor inspector.Cursor, message string) {
+	var stmt ast.Stmt
+	for c := range cursor.Enclosing() {
+		if s, ok := c.Node().(ast.Stmt); ok {
+			switch c.Parent().Node().(type) {
+			case *ast.BlockStmt, *ast.CaseClause, *ast.CommClause:
+				stmt = s
+			}
+		}
+		if stmt != nil {
+			break
+		}
+	}
+	if stmt == nil {
+		m.pass.Report(analysis.Diagnostic{
+			Pos:     cursor.Node().Pos(),
+			End:     cursor.Node().End(),
+			Message: message,
+		})
+		return
+	}
+	diagnostic := analysis.Diagnostic{
+		Pos:     cursor.Node().Pos(),
+		End:     cursor.Node().End(),
+		Message: message,
+	}
+	if !m.todoStmts[stmt] {
+		m.todoStmts[stmt] = true
+		diagnostic.SuggestedFixes = []analysis.SuggestedFix{{
+			Message: "Add TODO comment",
+			TextEdits: []analysis.TextEdit{{
+				Pos:     stmt.Pos(),
+				End:     stmt.Pos(),
+				NewText: []byte("// TODO(nupmigrate): " + message + "\n" + m.indent(stmt)),
+			}},
+		}}
+	}
+	m.pass.Report(diagnostic)
+}
+
+// indent returns the whitespace preceding node on its line.
+func (m *migrator) indent(node ast.Node) string {
+	file := m.pass.Fset.File(node.Pos())
+	content, err := m.pass.ReadFile(file.Name())
+	if err != nil {
+		return ""
+	}
+	offset := file.Offset(node.Pos())
+	lineStart := bytes.LastIndexByte(content[:offset], '\n') + 1
+	return string(content[lineStart:offset])
+}
+
+func isString(t types.Type) bool {
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Info()&types.IsString != 0
+}
+
+func isNumeric(t types.Type) bool {
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Info()&types.IsNumeric != 0
+}
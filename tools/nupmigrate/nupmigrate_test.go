@@ -0,0 +1,13 @@
+package nupmigrate
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	dir := filepath.Join(analysistest.TestData(), "app")
+	analysistest.RunWithSuggestedFixes(t, dir, Analyzer, "example.com/app/a", "example.com/app/b")
+}
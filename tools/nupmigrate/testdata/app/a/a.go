@@ -0,0 +1,46 @@
+package a
+
+import (
+	"time"
+
+	"github.com/nicheinc/nullable" // want `import of nullable v1`
+)
+
+type Patch struct {
+	Count   nullable.Int         // want `nullable.Int is now nup.Update\[int\]`
+	Name    nullable.String      // want `nullable.String is now nup.Update\[string\]`
+	Expires nullable.Time        // want `nullable.Time is now nup.Update\[time.Time\]`
+	Tags    nullable.StringSlice // want `nullable.StringSlice is now nup.SliceUpdate\[string\]`
+}
+
+func NewPatch(count *int, expires *time.Time, tags []string) Patch {
+	return Patch{
+		Count:   nullable.NewIntPtr(count),     // want `nullable.NewIntPtr is now nup.RemoveOrSet`
+		Name:    nullable.NewString("name"),    // want `nullable.NewString is now nup.Set`
+		Expires: nullable.NewTimePtr(expires),  // want `nullable.NewTimePtr is now nup.RemoveOrSet`
+		Tags:    nullable.NewStringSlice(tags), // want `nullable.NewStringSlice is now nup.SliceRemoveOrSet` `nil slice`
+	}
+}
+
+func Check(p Patch) bool {
+	if p.Count.Removed() || p.Count.Equals(5) { // want `Removed is now IsRemove` `Equals is now IsSetTo`
+		return false
+	}
+	if !p.Count.IsSet() { // want `IsSet no longer reports removals`
+		return false
+	}
+	negative := p.Count.IsNegative()              // want `IsNegative is now IsSetSuchThat`
+	zero := p.Count.IsZero()                      // want `IsZero is now IsSetTo\(0\)`
+	empty := p.Name.IsEmpty() || p.Tags.IsEmpty() // want `IsEmpty is now IsSetTo\(""\)` `IsEmpty is now IsSetSuchThat`
+	return negative || zero || empty
+}
+
+func Values(p Patch) (*int, *[]string) {
+	tags := p.Tags.Value()       // want `Value is now ValueOrNil` `ValueOrNil returns \[\]string`
+	return p.Count.Value(), tags // want `Value is now ValueOrNil`
+}
+
+func Unsupported(p *[]string) {
+	_ = nullable.NewStringSlicePtr(p) // want `nullable.NewStringSlicePtr has no nup equivalent`
+	_, _ = nullable.MarshalJSON(nil)  // want `nullable.MarshalJSON is now nup.MarshalJSON`
+}
@@ -0,0 +1,7 @@
+package b
+
+import "github.com/nicheinc/nullable" // want `import of nullable v1`
+
+type Patch struct {
+	Expires nullable.Time // want `nullable.Time is now nup.Update\[time.Time\]`
+}
@@ -0,0 +1,7 @@
+module example.com/app
+
+go 1.24
+
+require github.com/nicheinc/nullable v1.0.0
+
+replace github.com/nicheinc/nullable => ../nullable
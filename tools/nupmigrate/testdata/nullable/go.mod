@@ -0,0 +1,3 @@
+module github.com/nicheinc/nullable
+
+go 1.18
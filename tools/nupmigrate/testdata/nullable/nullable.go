@@ -0,0 +1,40 @@
+// Package nullable is a minimal stand-in for version 1 of the nullable
+// package.
+package nullable
+
+import "time"
+
+type Int struct{ value *int }
+
+func NewInt(v int) Int          { return Int{value: &v} }
+func NewIntPtr(p *int) Int      { return Int{value: p} }
+func (i Int) Value() *int       { return i.value }
+func (i Int) IsSet() bool       { return true }
+func (i Int) Removed() bool     { return i.value == nil }
+func (i Int) Equals(v int) bool { return i.value != nil && *i.value == v }
+func (i Int) IsZero() bool      { return i.Equals(0) }
+func (i Int) IsNegative() bool  { return i.value != nil && *i.value < 0 }
+
+type String struct{ value *string }
+
+func NewString(v string) String { return String{value: &v} }
+func (s String) Value() *string { return s.value }
+func (s String) IsSet() bool    { return true }
+func (s String) IsEmpty() bool  { return s.value != nil && *s.value == "" }
+
+type Time struct{ value *time.Time }
+
+func NewTimePtr(p *time.Time) Time { return Time{value: p} }
+func (t Time) Value() *time.Time   { return t.value }
+
+type StringSlice struct{ value *[]string }
+
+func NewStringSlice(v []string) StringSlice     { return StringSlice{value: &v} }
+func NewStringSlicePtr(p *[]string) StringSlice { return StringSlice{value: p} }
+func (s StringSlice) Value() *[]string          { return s.value }
+func (s StringSlice) IsSet() bool               { return true }
+func (s StringSlice) IsEmpty() bool             { return s.value != nil && len(*s.value) == 0 }
+
+type Nullable interface{ IsSet() bool }
+
+func MarshalJSON(v interface{}) ([]byte, error) { return nil, nil }
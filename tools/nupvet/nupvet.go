@@ -0,0 +1,254 @@
+/*
+Package nupvet defines an analyzer that reports uses of the nup package that
+are likely mistakes, particularly ones introduced when migrating from version 1
+of the nullable package.
+
+# IsSet used as a change check
+
+In version 1, IsSet returned true for set and removal updates alike. In version
+2, IsSet returns true only for set operations, and IsChange takes over the old
+meaning. Code like the following therefore silently ignores removals:
+
+	if !update.Name.IsSet() {
+		return
+	}
+	user.Name = update.Name.Apply(user.Name)
+
+The analyzer reports calls to IsSet whose result guards code that handles the
+update as a whole (by calling Apply, ApplyPtr, Diff, DiffPtr, Operation,
+ValueOperation, IsRemove, or IsChange on it, or by passing it elsewhere), and
+suggests replacing them with IsChange. Code guarded by IsSet that only inspects
+the set value, e.g. via Value or IsSetTo, is not reported.
+
+# Deprecated MarshalJSON
+
+As of Go 1.24, json.Marshal handles nup types correctly when their fields are
+marked with the "omitzero" struct tag, so nup.MarshalJSON is deprecated. The
+analyzer reports remaining calls to nup.MarshalJSON in packages whose module
+declares Go 1.24 or later.
+*/
+package nupvet
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"go/version"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const nupPath = "github.com/nicheinc/nullable/v2/nup"
+
+// Analyzer reports likely misuses of the nup package.
+var Analyzer = &analysis.Analyzer{
+	Name:     "nupvet",
+	Doc:      "report IsSet calls used as change checks and deprecated nup.MarshalJSON calls",
+	URL:      "https://pkg.go.dev/github.com/nicheinc/nullable/v2/tools/nupvet",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// wholeUpdateMethods are the Update and SliceUpdate methods whose results
+// depend on whether the update is a removal, and which therefore indicate that
+// an IsSet guard was meant to be a change check.
+var wholeUpdateMethods = map[string]bool{
+	"Apply":          true,
+	"ApplyPtr":       true,
+	"Diff":           true,
+	"DiffPtr":        true,
+	"Operation":      true,
+	"ValueOperation": true,
+	"IsRemove":       true,
+	"IsChange":       true,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	checkMarshalJSON := goVersionAtLeast(pass.Pkg.GoVersion(), "go1.24")
+	for cursor := range inspect.Root().Preorder((*ast.CallExpr)(nil)) {
+		call := cursor.Node().(*ast.CallExpr)
+		switch {
+		case checkMarshalJSON && isNupFunc(pass.TypesInfo, call.Fun, "MarshalJSON"):
+			pass.Report(analysis.Diagnostic{
+				Pos:     call.Pos(),
+				End:     call.End(),
+				Message: "nup.MarshalJSON is deprecated as of Go 1.24: use json.Marshal and mark nup fields with the omitzero struct tag",
+			})
+		case isIsSetCall(pass.TypesInfo, call):
+			checkIsSet(pass, cursor)
+		}
+	}
+	return nil, nil
+}
+
+// goVersionAtLeast reports whether the Go version v is known and at least min.
+func goVersionAtLeast(v, min string) bool {
+	return version.IsValid(v) && version.Compare(v, min) >= 0
+}
+
+// checkIsSet reports the IsSet call at cursor if it guards code that handles
+// the receiving update as a whole.
+func checkIsSet(pass *analysis.Pass, cursor inspector.Cursor) {
+	var (
+		call     = cursor.Node().(*ast.CallExpr)
+		selector = call.Fun.(*ast.SelectorExpr)
+		receiver = types.ExprString(selector.X)
+	)
+	// Find the if statement whose condition contains the call, noting whether
+	// the call is negated along the way.
+	negated := false
+	var ifCursor inspector.Cursor
+loop:
+	for parent := cursor.Parent(); ; parent = parent.Parent() {
+		switch node := parent.Node().(type) {
+		case *ast.ParenExpr:
+			continue
+		case *ast.UnaryExpr:
+			if node.Op == token.NOT {
+				negated = !negated
+				continue
+			}
+		case *ast.BinaryExpr:
+			if node.Op == token.LAND || node.Op == token.LOR {
+				continue
+			}
+		case *ast.IfStmt:
+			if node.Cond.Pos() <= call.Pos() && call.End() <= node.Cond.End() {
+				ifCursor = parent
+			}
+		}
+		break loop
+	}
+	if ifCursor.Node() == nil {
+		return
+	}
+	ifStmt := ifCursor.Node().(*ast.IfStmt)
+
+	// Positive checks guard the if body. Negated checks are early-outs that
+	// guard the remainder of the enclosing block.
+	var guarded []ast.Node
+	if !negated {
+		guarded = append(guarded, ifStmt.Body)
+	} else if terminates(ifStmt.Body) {
+		for sibling, ok := ifCursor.NextSibling(); ok; sibling, ok = sibling.NextSibling() {
+			guarded = append(guarded, sibling.Node())
+		}
+	}
+	for _, node := range guarded {
+		if usesWholeUpdate(pass.TypesInfo, node, receiver) {
+			pass.Report(analysis.Diagnostic{
+				Pos:     call.Pos(),
+				End:     call.End(),
+				Message: receiver + ".IsSet() is false for removals; use IsChange() to check for any change",
+				SuggestedFixes: []analysis.SuggestedFix{{
+					Message: "Replace IsSet with IsChange",
+					TextEdits: []analysis.TextEdit{{
+						Pos:     selector.Sel.Pos(),
+						End:     selector.Sel.End(),
+						NewText: []byte("IsChange"),
+					}},
+				}},
+			})
+			return
+		}
+	}
+}
+
+// terminates reports whether the block ends in a statement that leaves it
+// unconditionally.
+func terminates(block *ast.BlockStmt) bool {
+	if len(block.List) == 0 {
+		return false
+	}
+	switch stmt := block.List[len(block.List)-1].(type) {
+	case *ast.ReturnStmt, *ast.BranchStmt:
+		return true
+	case *ast.ExprStmt:
+		call, ok := stmt.X.(*ast.CallExpr)
+		if !ok {
+			return false
+		}
+		ident, ok := call.Fun.(*ast.Ident)
+		return ok && ident.Name == "panic"
+	}
+	return false
+}
+
+// usesWholeUpdate reports whether node contains a use of the update expression
+// receiver other than as the receiver of a set-only method like Value.
+func usesWholeUpdate(info *types.Info, node ast.Node, receiver string) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if found {
+			return false
+		}
+		// Method calls on the receiver count only if the method's result
+		// depends on whether the update is a removal. Don't descend into the
+		// receiver, which would otherwise be counted as a bare use.
+		if selector, ok := n.(*ast.SelectorExpr); ok && matches(info, selector.X, receiver) {
+			found = wholeUpdateMethods[selector.Sel.Name]
+			return false
+		}
+		if expr, ok := n.(ast.Expr); ok && matches(info, expr, receiver) {
+			found = true
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// matches reports whether expr is an update expression spelled the same way as
+// receiver.
+func matches(info *types.Info, expr ast.Expr, receiver string) bool {
+	return types.ExprString(expr) == receiver && isUpdate(info.TypeOf(expr))
+}
+
+// isIsSetCall reports whether call is a call to the IsSet method of an Update
+// or SliceUpdate.
+func isIsSetCall(info *types.Info, call *ast.CallExpr) bool {
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || selector.Sel.Name != "IsSet" || len(call.Args) != 0 {
+		return false
+	}
+	return isUpdate(info.TypeOf(selector.X))
+}
+
+// isUpdate reports whether t is an instance of Update or SliceUpdate, or a
+// pointer to one.
+func isUpdate(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	if ptr, ok := types.Unalias(t).(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Origin().Obj()
+	if obj.Pkg() == nil || obj.Pkg().Path() != nupPath {
+		return false
+	}
+	return obj.Name() == "Update" || obj.Name() == "SliceUpdate"
+}
+
+// isNupFunc reports whether expr refers to the nup package-level function with
+// the given name.
+func isNupFunc(info *types.Info, expr ast.Expr, name string) bool {
+	var ident *ast.Ident
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		ident = expr
+	case *ast.SelectorExpr:
+		ident = expr.Sel
+	default:
+		return false
+	}
+	fn, ok := info.Uses[ident].(*types.Func)
+	return ok && fn.Pkg() != nil && fn.Pkg().Path() == nupPath && fn.Name() == name
+}
@@ -0,0 +1,34 @@
+package nupvet
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	testCases := []struct {
+		name    string
+		dir     string
+		pattern string
+	}{
+		{
+			name:    "Go1.24",
+			dir:     "go124",
+			pattern: "example.com/go124/a",
+		},
+		{
+			name:    "Go1.23",
+			dir:     "go123",
+			pattern: "example.com/go123/b",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			dir := filepath.Join(analysistest.TestData(), testCase.dir)
+			analysistest.RunWithSuggestedFixes(t, dir, Analyzer, testCase.pattern)
+		})
+	}
+}
@@ -0,0 +1,7 @@
+package b
+
+import "github.com/nicheinc/nullable/v2/nup"
+
+func marshal(name nup.Update[string]) {
+	nup.MarshalJSON(name)
+}
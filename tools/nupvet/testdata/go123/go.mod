@@ -0,0 +1,7 @@
+module example.com/go123
+
+go 1.23
+
+require github.com/nicheinc/nullable/v2 v2.0.0
+
+replace github.com/nicheinc/nullable/v2 => ../nullable
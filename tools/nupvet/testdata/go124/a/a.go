@@ -0,0 +1,62 @@
+package a
+
+import "github.com/nicheinc/nullable/v2/nup"
+
+type user struct {
+	Name string
+	Age  *int
+	Tags []string
+}
+
+type patch struct {
+	Name nup.Update[string]
+	Age  nup.Update[int]
+	Tags nup.SliceUpdate[string]
+}
+
+func earlyOut(u *user, p patch) {
+	if !p.Name.IsSet() { // want `p.Name.IsSet\(\) is false for removals; use IsChange\(\) to check for any change`
+		return
+	}
+	u.Name = p.Name.Apply(u.Name)
+}
+
+func guardedWrite(u *user, p patch) {
+	if p.Age.IsSet() { // want `p.Age.IsSet\(\) is false for removals`
+		u.Age = p.Age.ApplyPtr(u.Age)
+	}
+	if p.Tags.IsSet() { // want `p.Tags.IsSet\(\) is false for removals`
+		u.Tags = p.Tags.Apply(u.Tags)
+	}
+}
+
+func passedAlong(p patch) {
+	if p.Name.IsSet() && p.Age.IsSet() { // want `p.Name.IsSet\(\) is false for removals`
+		save(p.Name)
+	}
+}
+
+func save(nup.Update[string]) {}
+
+func valueOnly(u *user, p patch) {
+	if p.Name.IsSet() {
+		u.Name, _ = p.Name.Value()
+	}
+	if !p.Name.IsSet() {
+		return
+	}
+	if p.Name.IsSetTo("") {
+		u.Name = "anonymous"
+	}
+}
+
+func notEarlyOut(u *user, p patch) {
+	if !p.Name.IsSet() {
+		u.Name = "default"
+	}
+	u.Name = p.Name.Apply(u.Name)
+}
+
+func marshal(p patch) {
+	nup.MarshalJSON(p) // want `nup.MarshalJSON is deprecated as of Go 1.24`
+}
@@ -0,0 +1,7 @@
+module example.com/go124
+
+go 1.24
+
+require github.com/nicheinc/nullable/v2 v2.0.0
+
+replace github.com/nicheinc/nullable/v2 => ../nullable
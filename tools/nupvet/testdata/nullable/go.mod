@@ -0,0 +1,3 @@
+module github.com/nicheinc/nullable/v2
+
+go 1.18
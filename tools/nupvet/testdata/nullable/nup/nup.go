@@ -0,0 +1,32 @@
+// Package nup is a minimal stand-in for the real nup package.
+package nup
+
+type Operation byte
+
+type Update[T comparable] struct {
+	op    Operation
+	value T
+}
+
+func Set[T comparable](value T) Update[T] { return Update[T]{op: 2, value: value} }
+
+func (u Update[T]) Operation() Operation           { return u.op }
+func (u Update[T]) IsSet() bool                    { return u.op == 2 }
+func (u Update[T]) IsRemove() bool                 { return u.op == 1 }
+func (u Update[T]) IsChange() bool                 { return u.op != 0 }
+func (u Update[T]) Value() (T, bool)               { return u.value, u.op == 2 }
+func (u Update[T]) IsSetTo(value T) bool           { return u.op == 2 && u.value == value }
+func (u Update[T]) Apply(value T) T                { return value }
+func (u Update[T]) ApplyPtr(value *T) *T           { return value }
+func (u Update[T]) ValueOperation() (T, Operation) { return u.value, u.op }
+
+type SliceUpdate[T comparable] struct {
+	op    Operation
+	value []T
+}
+
+func (u SliceUpdate[T]) IsSet() bool         { return u.op == 2 }
+func (u SliceUpdate[T]) Apply(value []T) []T { return value }
+func (u SliceUpdate[T]) Value() ([]T, bool)  { return u.value, u.op == 2 }
+
+func MarshalJSON(v interface{}) ([]byte, error) { return nil, nil }